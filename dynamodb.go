@@ -0,0 +1,1142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dynamodbMaxProbeItems is the maximum number of probe items tracked for the cleanup on shutdown.
+// When a long outage keeps the checks from deleting their probe items, the oldest ones are no longer tracked,
+// so that the cleanup neither grows without bound nor outlasts its timeout.
+const dynamodbMaxProbeItems = 100
+
+// statusLostUpdate is the status of a conditional write that failed because the probe item
+// was not in the state written by the previous operation.
+const statusLostUpdate = "LostUpdate"
+
+// dynamodbKey is a key attribute of the probe item.
+type dynamodbKey struct {
+	name string
+	// typ is the scalar attribute type of the key, which is S, N, or B.
+	typ   dynamodbtypes.ScalarAttributeType
+	value string
+	// unique is true when the key is the unique ID of the probe item instead of value.
+	unique bool
+}
+
+// configureDynamoDB configures the key schema and the probe item of the DynamoDB check,
+// and the optional DynamoDB checks from the environment variables.
+func (c *checker) configureDynamoDB(cfg aws.Config) error {
+	var err error
+
+	c.dynamodbPartitionKey, err = dynamodbKeyFromEnv("DYNAMODB_PARTITION_KEY", "id")
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("DYNAMODB_SORT_KEY") != "" {
+		c.dynamodbSortKey, err = dynamodbKeyFromEnv("DYNAMODB_SORT_KEY", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	if v := os.Getenv("DYNAMODB_ITEM_ATTRIBUTES"); v != "" {
+		c.dynamodbItemAttributes, err = parseDynamoDBItemAttributes(v)
+		if err != nil {
+			return fmt.Errorf("invalid DYNAMODB_ITEM_ATTRIBUTES %q, %v", v, err)
+		}
+	}
+
+	// The unique ID of the probe item is written to the partition key if possible,
+	// so that the probe items of the checker instances never share a partition.
+	switch {
+	case c.dynamodbPartitionKey.typ != dynamodbtypes.ScalarAttributeTypeN:
+		c.dynamodbPartitionKey.unique = true
+	case c.dynamodbSortKey != nil && c.dynamodbSortKey.typ != dynamodbtypes.ScalarAttributeTypeN:
+		c.dynamodbSortKey.unique = true
+	default:
+		// Both keys are numbers, so a numeric unique ID is written to the partition key
+		c.dynamodbPartitionKey.unique = true
+	}
+
+	if err := c.dynamodbPartitionKey.setFixedValue("DYNAMODB_PARTITION_KEY"); err != nil {
+		return err
+	}
+
+	if c.dynamodbSortKey != nil {
+		if err := c.dynamodbSortKey.setFixedValue("DYNAMODB_SORT_KEY"); err != nil {
+			return err
+		}
+	}
+
+	c.dynamodbProbeKeyPrefix = os.Getenv("DYNAMODB_PROBE_KEY_PREFIX")
+	if c.dynamodbProbeKeyPrefix == "" {
+		c.dynamodbProbeKeyPrefix = "aws-checker"
+	}
+
+	c.dynamodbProbeItems = make(map[string]time.Time)
+
+	c.dynamodbTransactionCheck, err = envBool("DYNAMODB_TRANSACTION_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.dynamodbBatchCheck, err = envBool("DYNAMODB_BATCH_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.dynamodbPartiQLCheck, err = envBool("DYNAMODB_PARTIQL_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.dynamodbConditionalWriteCheck, err = envBool("DYNAMODB_CONDITIONAL_WRITE_CHECK")
+	if err != nil {
+		return err
+	}
+
+	readLimit, err := envInt("DYNAMODB_READ_LIMIT", 1)
+	if err != nil {
+		return err
+	}
+	if readLimit < 1 {
+		return fmt.Errorf("DYNAMODB_READ_LIMIT must be at least 1, got %d", readLimit)
+	}
+	c.dynamodbReadLimit = int32(readLimit)
+
+	if v := os.Getenv("DYNAMODB_REPLICA_REGIONS"); v != "" {
+		c.dynamodbReplicaClients = make(map[string]*dynamodb.Client)
+		for _, region := range strings.Split(v, ",") {
+			region = strings.TrimSpace(region)
+			opts := append([]func(*dynamodb.Options){}, c.dynamodbOpts...)
+			opts = append(opts, func(o *dynamodb.Options) {
+				o.Region = region
+			})
+			c.dynamodbReplicaClients[region] = dynamodb.NewFromConfig(cfg, opts...)
+		}
+
+		c.dynamodbReplicationTimeout, err = envDuration("DYNAMODB_REPLICATION_TIMEOUT", 5*time.Minute)
+		if err != nil {
+			return err
+		}
+
+		c.dynamodbReplicationPollInterval, err = envDuration("DYNAMODB_REPLICATION_POLL_INTERVAL", time.Second)
+		if err != nil {
+			return err
+		}
+	}
+
+	dynamodbStreamsCheck, err := envBool("DYNAMODB_STREAMS_CHECK")
+	if err != nil {
+		return err
+	}
+
+	if dynamodbStreamsCheck {
+		c.dynamodbStreamsClient = dynamodbstreams.NewFromConfig(cfg, c.dynamodbStreamsOpts...)
+
+		c.dynamodbStreamsTimeout, err = envDuration("DYNAMODB_STREAMS_TIMEOUT", time.Minute)
+		if err != nil {
+			return err
+		}
+
+		c.dynamodbStreamsPollInterval, err = envDuration("DYNAMODB_STREAMS_POLL_INTERVAL", time.Second)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.dynamodbReplicationLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aws_dynamodb_replication_lag_seconds",
+			Help:    "Time until an item written to the global table in the source region shows up in the replica region.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{"source_region", "replica_region", "status"},
+	)
+
+	return nil
+}
+
+// dynamodbKeyFromEnv returns the key attribute configured by the <prefix>, <prefix>_TYPE, and <prefix>_VALUE environment variables.
+// The value is defaulted and validated by setFixedValue once it is known whether the key is unique.
+func dynamodbKeyFromEnv(prefix, defaultName string) (*dynamodbKey, error) {
+	k := &dynamodbKey{
+		name:  os.Getenv(prefix),
+		typ:   dynamodbtypes.ScalarAttributeType(os.Getenv(prefix + "_TYPE")),
+		value: os.Getenv(prefix + "_VALUE"),
+	}
+
+	if k.name == "" {
+		k.name = defaultName
+	}
+
+	if k.typ == "" {
+		k.typ = dynamodbtypes.ScalarAttributeTypeS
+	}
+
+	switch k.typ {
+	case dynamodbtypes.ScalarAttributeTypeS, dynamodbtypes.ScalarAttributeTypeB, dynamodbtypes.ScalarAttributeTypeN:
+	default:
+		return nil, fmt.Errorf("%s_TYPE must be one of S, N, and B, got %q", prefix, k.typ)
+	}
+
+	return k, nil
+}
+
+// setFixedValue defaults and validates the value of the key configured by the <prefix>_VALUE environment variable,
+// which is written to every probe item unless the key is unique.
+func (k *dynamodbKey) setFixedValue(prefix string) error {
+	if k.unique {
+		return nil
+	}
+
+	if k.typ == dynamodbtypes.ScalarAttributeTypeN {
+		if k.value == "" {
+			k.value = "0"
+		}
+
+		if _, err := strconv.ParseFloat(k.value, 64); err != nil {
+			return fmt.Errorf("%s_VALUE must be a number for the key type N, got %q", prefix, k.value)
+		}
+		return nil
+	}
+
+	if k.value == "" {
+		k.value = "test-id"
+	}
+	return nil
+}
+
+// attributeValue returns the key of the probe item with the ID.
+func (k *dynamodbKey) attributeValue(id string) dynamodbtypes.AttributeValue {
+	value := k.value
+	if k.unique {
+		value = id
+	}
+
+	switch k.typ {
+	case dynamodbtypes.ScalarAttributeTypeN:
+		return &dynamodbtypes.AttributeValueMemberN{Value: value}
+	case dynamodbtypes.ScalarAttributeTypeB:
+		return &dynamodbtypes.AttributeValueMemberB{Value: []byte(value)}
+	default:
+		return &dynamodbtypes.AttributeValueMemberS{Value: value}
+	}
+}
+
+// parseDynamoDBItemAttributes parses a flat JSON object into item attributes.
+// Strings, numbers, and booleans are converted to the S, N, and BOOL types respectively.
+func parseDynamoDBItemAttributes(v string) (map[string]dynamodbtypes.AttributeValue, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(v)))
+	dec.UseNumber()
+
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]dynamodbtypes.AttributeValue, len(m))
+	for name, value := range m {
+		switch value := value.(type) {
+		case string:
+			attrs[name] = &dynamodbtypes.AttributeValueMemberS{Value: value}
+		case json.Number:
+			attrs[name] = &dynamodbtypes.AttributeValueMemberN{Value: value.String()}
+		case bool:
+			attrs[name] = &dynamodbtypes.AttributeValueMemberBOOL{Value: value}
+		default:
+			return nil, fmt.Errorf("attribute %q must be a string, number, or boolean", name)
+		}
+	}
+
+	return attrs, nil
+}
+
+// dynamodbProbeID returns a new ID of the probe item, which is unique to this checker instance and call.
+func (c *checker) dynamodbProbeID() string {
+	// A number can hold neither the prefix nor the instance ID
+	if c.dynamodbPartitionKey.unique && c.dynamodbPartitionKey.typ == dynamodbtypes.ScalarAttributeTypeN {
+		return c.probeNumber()
+	}
+
+	return c.dynamodbProbeKeyPrefix + "-" + c.probeID()
+}
+
+// dynamodbProbeKey returns the primary key of the probe item with the ID.
+func (c *checker) dynamodbProbeKey(id string) map[string]dynamodbtypes.AttributeValue {
+	key := map[string]dynamodbtypes.AttributeValue{
+		c.dynamodbPartitionKey.name: c.dynamodbPartitionKey.attributeValue(id),
+	}
+
+	if c.dynamodbSortKey != nil {
+		key[c.dynamodbSortKey.name] = c.dynamodbSortKey.attributeValue(id)
+	}
+
+	return key
+}
+
+// dynamodbProbeItem returns the probe item with the ID and the data attribute.
+func (c *checker) dynamodbProbeItem(id, data string) map[string]dynamodbtypes.AttributeValue {
+	item := c.dynamodbProbeKey(id)
+
+	for name, value := range c.dynamodbItemAttributes {
+		item[name] = value
+	}
+
+	item["data"] = &dynamodbtypes.AttributeValueMemberS{Value: data}
+
+	return item
+}
+
+// dynamodbProbeQuery returns the query for the probe item with the ID,
+// which matches the whole primary key so that it never reads other items.
+func (c *checker) dynamodbProbeQuery(id string, consistentRead bool) *dynamodb.QueryInput {
+	in := &dynamodb.QueryInput{
+		TableName:              &c.dynamodbTable,
+		KeyConditionExpression: aws.String("#pk = :pk"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk": c.dynamodbPartitionKey.name,
+		},
+		ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":pk": c.dynamodbPartitionKey.attributeValue(id),
+		},
+		Limit: &c.dynamodbReadLimit,
+	}
+
+	if c.dynamodbSortKey != nil {
+		in.KeyConditionExpression = aws.String("#pk = :pk AND #sk = :sk")
+		in.ExpressionAttributeNames["#sk"] = c.dynamodbSortKey.name
+		in.ExpressionAttributeValues[":sk"] = c.dynamodbSortKey.attributeValue(id)
+	}
+
+	if consistentRead {
+		in.ConsistentRead = aws.Bool(true)
+	}
+
+	return in
+}
+
+// putDynamoDBProbeItem creates the probe item with the ID.
+// It fails with statusLostUpdate if the item already exists.
+func (c *checker) putDynamoDBProbeItem(ctx context.Context, id, data string) error {
+	c.trackDynamoDBProbeItems(id)
+
+	_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &c.dynamodbTable,
+		Item:                c.dynamodbProbeItem(id, data),
+		ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk": c.dynamodbPartitionKey.name,
+		},
+	})
+	if dynamodbWriteRejected(err) {
+		c.untrackDynamoDBProbeItems(id)
+	}
+	return dynamodbConditionError(err, "probe item %s already exists", id)
+}
+
+// deleteDynamoDBProbeItem deletes the probe item with the ID.
+// If data is not empty, it fails with statusLostUpdate unless the item has the data.
+func (c *checker) deleteDynamoDBProbeItem(ctx context.Context, id, data string) error {
+	in := &dynamodb.DeleteItemInput{
+		TableName: &c.dynamodbTable,
+		Key:       c.dynamodbProbeKey(id),
+	}
+
+	if data != "" {
+		in.ConditionExpression = aws.String("#data = :data")
+		in.ExpressionAttributeNames = map[string]string{
+			"#data": "data",
+		}
+		in.ExpressionAttributeValues = map[string]dynamodbtypes.AttributeValue{
+			":data": &dynamodbtypes.AttributeValueMemberS{Value: data},
+		}
+	}
+
+	_, err := c.dynamoClient.DeleteItem(ctx, in)
+
+	// The item is left as is when the condition fails, so it is still deleted by the cleanup on shutdown
+	if err == nil {
+		c.untrackDynamoDBProbeItems(id)
+	}
+
+	return dynamodbConditionError(err, "probe item %s does not have the data %q", id, data)
+}
+
+// trackDynamoDBProbeItems records the IDs of the probe items about to be written,
+// so that they are deleted by the cleanup on shutdown unless deleted by the check.
+// Up to dynamodbMaxProbeItems IDs are tracked, and the oldest ones are dropped beyond that.
+func (c *checker) trackDynamoDBProbeItems(ids ...string) {
+	c.trackDynamoDBProbeItemsAt(time.Now(), ids...)
+}
+
+// trackDynamoDBProbeItemsAt is trackDynamoDBProbeItems with the time the probe items are written at,
+// which decides the oldest ones to drop.
+func (c *checker) trackDynamoDBProbeItemsAt(now time.Time, ids ...string) {
+	c.dynamodbProbeItemsMu.Lock()
+	defer c.dynamodbProbeItemsMu.Unlock()
+
+	for _, id := range ids {
+		c.dynamodbProbeItems[id] = now
+	}
+
+	for len(c.dynamodbProbeItems) > dynamodbMaxProbeItems {
+		var (
+			oldest   string
+			oldestAt time.Time
+		)
+		for id, at := range c.dynamodbProbeItems {
+			if oldest == "" || at.Before(oldestAt) {
+				oldest, oldestAt = id, at
+			}
+		}
+
+		log.Printf("too many DynamoDB probe items are left behind, probe item %s will not be cleaned up", oldest)
+		delete(c.dynamodbProbeItems, oldest)
+	}
+}
+
+// untrackDynamoDBProbeItems forgets the IDs of the probe items that have been deleted or never written.
+func (c *checker) untrackDynamoDBProbeItems(ids ...string) {
+	c.dynamodbProbeItemsMu.Lock()
+	defer c.dynamodbProbeItemsMu.Unlock()
+
+	for _, id := range ids {
+		delete(c.dynamodbProbeItems, id)
+	}
+}
+
+// dynamodbWriteRejected returns true if DynamoDB definitely rejected the write, so that nothing has been written,
+// like on AccessDeniedException, ResourceNotFoundException, or throttling.
+// Other errors, like timeouts and server errors, leave it unknown whether the write has been applied.
+//
+// A failed attribute_not_exists condition is not a rejection, as it means the item exists.
+func dynamodbWriteRejected(err error) bool {
+	var condErr *dynamodbtypes.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return false
+	}
+
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode()/100 == 4
+}
+
+// cleanupDynamoDBProbeItems deletes the probe items left behind by the checks that were stopped halfway.
+func (c *checker) cleanupDynamoDBProbeItems(ctx context.Context) {
+	c.dynamodbProbeItemsMu.Lock()
+	ids := make([]string, 0, len(c.dynamodbProbeItems))
+	for id := range c.dynamodbProbeItems {
+		ids = append(ids, id)
+	}
+	c.dynamodbProbeItemsMu.Unlock()
+
+	for i, id := range ids {
+		if ctx.Err() != nil {
+			log.Printf("failed to clean up %d DynamoDB probe items, %v", len(ids)-i, ctx.Err())
+			return
+		}
+
+		if err := c.deleteDynamoDBProbeItem(ctx, id, ""); err != nil {
+			log.Printf("failed to clean up DynamoDB probe item %s, %v", id, err)
+		}
+	}
+}
+
+// dynamodbConditionError returns err with statusLostUpdate and the message if the condition of a write failed,
+// and err as is otherwise.
+func dynamodbConditionError(err error, format string, args ...any) error {
+	var condErr *dynamodbtypes.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return withStatus(statusLostUpdate, fmt.Errorf(format+", %v", append(args, err)...))
+	}
+
+	return err
+}
+
+func (c *checker) doCheckDynamoDB(ctx context.Context) {
+	// Every check writes its own probe item, so that the checks of the checker instances
+	// sharing the table do not overwrite or delete the item of each other.
+	var (
+		id  = c.dynamodbProbeID()
+		ids = []string{id}
+	)
+
+	ops := []operation{
+		{
+			method: "Scan",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.Scan(ctx, &dynamodb.ScanInput{
+						TableName: &c.dynamodbTable,
+						Limit:     &c.dynamodbReadLimit,
+					})
+					return err
+				},
+			},
+		},
+		{
+			method: "PutItem",
+			operations: []func() error{
+				func() error {
+					return c.putDynamoDBProbeItem(ctx, id, "test-data")
+				},
+			},
+		},
+		{
+			method: "UpdateItem",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+						TableName:           &c.dynamodbTable,
+						Key:                 c.dynamodbProbeKey(id),
+						UpdateExpression:    aws.String("SET #data = :data"),
+						ConditionExpression: aws.String("#data = :expected"),
+						ExpressionAttributeNames: map[string]string{
+							"#data": "data",
+						},
+						ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
+							":data":     &dynamodbtypes.AttributeValueMemberS{Value: "updated-data"},
+							":expected": &dynamodbtypes.AttributeValueMemberS{Value: "test-data"},
+						},
+					})
+					return dynamodbConditionError(err, "probe item %s does not have the data %q", id, "test-data")
+				},
+			},
+		},
+		{
+			method: "DeleteItem",
+			operations: []func() error{
+				func() error {
+					return c.deleteDynamoDBProbeItem(ctx, id, "updated-data")
+				},
+			},
+		},
+		{
+			method: "GetItem",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+						TableName: &c.dynamodbTable,
+						Key:       c.dynamodbProbeKey(id),
+					})
+					return err
+				},
+			},
+		},
+		{
+			method: "GetItemConsistent",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+						TableName:      &c.dynamodbTable,
+						Key:            c.dynamodbProbeKey(id),
+						ConsistentRead: aws.Bool(true),
+					})
+					return err
+				},
+			},
+		},
+		{
+			method: "Query",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.Query(ctx, c.dynamodbProbeQuery(id, false))
+					return err
+				},
+			},
+		},
+		{
+			method: "QueryConsistent",
+			operations: []func() error{
+				func() error {
+					_, err := c.dynamoClient.Query(ctx, c.dynamodbProbeQuery(id, true))
+					return err
+				},
+			},
+		},
+		{
+			method: "PutGetItemConsistent",
+			operations: []func() error{
+				func() error {
+					return c.putDynamoDBProbeItem(ctx, id, "test-data")
+				},
+				func() error {
+					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+						TableName:      &c.dynamodbTable,
+						Key:            c.dynamodbProbeKey(id),
+						ConsistentRead: aws.Bool(true),
+					})
+					return err
+				},
+			},
+		},
+	}
+
+	if c.dynamodbTransactionCheck {
+		txIDs := []string{c.dynamodbProbeID(), c.dynamodbProbeID()}
+		ids = append(ids, txIDs...)
+		ops = append(ops, c.dynamodbTransactionOperations(ctx, txIDs)...)
+	}
+
+	if c.dynamodbBatchCheck {
+		batchIDs := []string{c.dynamodbProbeID(), c.dynamodbProbeID()}
+		ids = append(ids, batchIDs...)
+		ops = append(ops, c.dynamodbBatchOperations(ctx, batchIDs)...)
+	}
+
+	if c.dynamodbPartiQLCheck {
+		partiQLID := c.dynamodbProbeID()
+		ids = append(ids, partiQLID)
+		ops = append(ops, c.dynamodbPartiQLOperations(ctx, partiQLID)...)
+	}
+
+	if c.dynamodbConditionalWriteCheck {
+		conditionalID := c.dynamodbProbeID()
+		ids = append(ids, conditionalID)
+		ops = append(ops, c.dynamodbConditionalWriteOperations(ctx, conditionalID)...)
+	}
+
+	if c.dynamodbStreamsClient != nil {
+		streamID := c.dynamodbProbeID()
+		ids = append(ids, streamID)
+		ops = append(ops, c.dynamodbStreamsOperations(ctx, streamID)...)
+	}
+
+	c.doCheckService(ctx, "DynamoDB", ops)
+
+	// Delete the items written by PutGetItemConsistent and the optional operations.
+	// The items left behind by a canceled check are deleted by the cleanup on shutdown.
+	if ctx.Err() == nil {
+		for _, id := range ids {
+			if err := c.deleteDynamoDBProbeItem(ctx, id, ""); err != nil {
+				log.Printf("failed to delete DynamoDB probe item %s, %v", id, err)
+			}
+		}
+	}
+}
+
+// dynamodbTransactionOperations returns the operations to write the probe items with the IDs in a transaction,
+// and read them back in a transaction.
+func (c *checker) dynamodbTransactionOperations(ctx context.Context, ids []string) []operation {
+	return []operation{
+		{
+			method: "TransactWriteItems",
+			operations: []func() error{
+				func() error {
+					c.trackDynamoDBProbeItems(ids...)
+
+					var items []dynamodbtypes.TransactWriteItem
+					for _, id := range ids {
+						items = append(items, dynamodbtypes.TransactWriteItem{
+							Put: &dynamodbtypes.Put{
+								TableName:           &c.dynamodbTable,
+								Item:                c.dynamodbProbeItem(id, "test-data"),
+								ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+								ExpressionAttributeNames: map[string]string{
+									"#pk": c.dynamodbPartitionKey.name,
+								},
+							},
+						})
+					}
+
+					_, err := c.dynamoClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+						TransactItems: items,
+					})
+					// A canceled transaction writes none of the items
+					if dynamodbWriteRejected(err) {
+						c.untrackDynamoDBProbeItems(ids...)
+					}
+					return err
+				},
+			},
+		},
+		{
+			method: "TransactGetItems",
+			operations: []func() error{
+				func() error {
+					var items []dynamodbtypes.TransactGetItem
+					for _, id := range ids {
+						items = append(items, dynamodbtypes.TransactGetItem{
+							Get: &dynamodbtypes.Get{
+								TableName: &c.dynamodbTable,
+								Key:       c.dynamodbProbeKey(id),
+							},
+						})
+					}
+
+					res, err := c.dynamoClient.TransactGetItems(ctx, &dynamodb.TransactGetItemsInput{
+						TransactItems: items,
+					})
+					if err != nil {
+						return err
+					}
+
+					for i, r := range res.Responses {
+						if r.Item == nil {
+							return withStatus(statusLostUpdate, fmt.Errorf("probe item %s written in the transaction is not found", ids[i]))
+						}
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// dynamodbBatchOperations returns the operations to write the probe items with the IDs in a batch,
+// and read them back in a batch.
+//
+// Unprocessed items are reported as failures instead of being retried,
+// because they mean the table is throttled.
+func (c *checker) dynamodbBatchOperations(ctx context.Context, ids []string) []operation {
+	return []operation{
+		{
+			method: "BatchWriteItem",
+			operations: []func() error{
+				func() error {
+					c.trackDynamoDBProbeItems(ids...)
+
+					var requests []dynamodbtypes.WriteRequest
+					for _, id := range ids {
+						requests = append(requests, dynamodbtypes.WriteRequest{
+							PutRequest: &dynamodbtypes.PutRequest{
+								Item: c.dynamodbProbeItem(id, "test-data"),
+							},
+						})
+					}
+
+					res, err := c.dynamoClient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+						RequestItems: map[string][]dynamodbtypes.WriteRequest{
+							c.dynamodbTable: requests,
+						},
+					})
+					if dynamodbWriteRejected(err) {
+						c.untrackDynamoDBProbeItems(ids...)
+					}
+					if err != nil {
+						return err
+					}
+
+					if n := len(res.UnprocessedItems[c.dynamodbTable]); n > 0 {
+						return fmt.Errorf("%d of %d items are unprocessed", n, len(requests))
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "BatchGetItem",
+			operations: []func() error{
+				func() error {
+					var keys []map[string]dynamodbtypes.AttributeValue
+					for _, id := range ids {
+						keys = append(keys, c.dynamodbProbeKey(id))
+					}
+
+					res, err := c.dynamoClient.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+						RequestItems: map[string]dynamodbtypes.KeysAndAttributes{
+							c.dynamodbTable: {
+								Keys:           keys,
+								ConsistentRead: aws.Bool(true),
+							},
+						},
+					})
+					if err != nil {
+						return err
+					}
+
+					if n := len(res.UnprocessedKeys[c.dynamodbTable].Keys); n > 0 {
+						return fmt.Errorf("%d of %d keys are unprocessed", n, len(keys))
+					}
+
+					if n := len(res.Responses[c.dynamodbTable]); n != len(keys) {
+						return withStatus(statusLostUpdate, fmt.Errorf("%d of %d probe items written in the batch are found", n, len(keys)))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// dynamodbPartiQLOperations returns the operations to update the probe item with the ID by a PartiQL UPDATE statement,
+// and read it back by a PartiQL SELECT statement.
+func (c *checker) dynamodbPartiQLOperations(ctx context.Context, id string) []operation {
+	where, params := c.dynamodbProbePartiQLWhere(id)
+
+	return []operation{
+		{
+			method: "ExecuteStatementUpdate",
+			operations: []func() error{
+				func() error {
+					// PartiQL UPDATE fails unless the item exists
+					return c.putDynamoDBProbeItem(ctx, id, "test-data")
+				},
+				func() error {
+					_, err := c.dynamoClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+						Statement:  aws.String(fmt.Sprintf(`UPDATE %q SET "data" = ? WHERE %s`, c.dynamodbTable, where)),
+						Parameters: append([]dynamodbtypes.AttributeValue{&dynamodbtypes.AttributeValueMemberS{Value: "updated-data"}}, params...),
+					})
+					return err
+				},
+			},
+		},
+		{
+			method: "ExecuteStatementSelect",
+			operations: []func() error{
+				func() error {
+					res, err := c.dynamoClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+						Statement:      aws.String(fmt.Sprintf(`SELECT * FROM %q WHERE %s`, c.dynamodbTable, where)),
+						Parameters:     params,
+						ConsistentRead: aws.Bool(true),
+					})
+					if err != nil {
+						return err
+					}
+
+					if len(res.Items) != 1 {
+						return withStatus(statusLostUpdate, fmt.Errorf("probe item %s is not found", id))
+					}
+
+					if data, ok := res.Items[0]["data"].(*dynamodbtypes.AttributeValueMemberS); !ok || data.Value != "updated-data" {
+						return withStatus(statusLostUpdate, fmt.Errorf("probe item %s does not have the data %q", id, "updated-data"))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// dynamodbProbePartiQLWhere returns the PartiQL WHERE clause matching the primary key of the probe item with the ID,
+// and its parameters.
+func (c *checker) dynamodbProbePartiQLWhere(id string) (string, []dynamodbtypes.AttributeValue) {
+	where := fmt.Sprintf("%q = ?", c.dynamodbPartitionKey.name)
+	params := []dynamodbtypes.AttributeValue{c.dynamodbPartitionKey.attributeValue(id)}
+
+	if c.dynamodbSortKey != nil {
+		where += fmt.Sprintf(" AND %q = ?", c.dynamodbSortKey.name)
+		params = append(params, c.dynamodbSortKey.attributeValue(id))
+	}
+
+	return where, params
+}
+
+// dynamodbConditionalWriteOperations returns the operation to write the probe item with the ID,
+// and write it again with the condition that it does not exist, which must be rejected.
+func (c *checker) dynamodbConditionalWriteOperations(ctx context.Context, id string) []operation {
+	return []operation{
+		{
+			method: "ConditionalPutItem",
+			operations: []func() error{
+				func() error {
+					return c.putDynamoDBProbeItem(ctx, id, "test-data")
+				},
+				expectError("ConditionalCheckFailedException", func() error {
+					_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+						TableName:           &c.dynamodbTable,
+						Item:                c.dynamodbProbeItem(id, "overwritten-data"),
+						ConditionExpression: aws.String("attribute_not_exists(#pk)"),
+						ExpressionAttributeNames: map[string]string{
+							"#pk": c.dynamodbPartitionKey.name,
+						},
+					})
+					return err
+				}),
+			},
+		},
+	}
+}
+
+// dynamodbStreamsOperations returns the operations to write the probe item with the ID,
+// and read the stream of the table until the change record of the item shows up.
+//
+// The shard iterators are taken before writing the item, so that reading from the latest records
+// never misses the change record.
+func (c *checker) dynamodbStreamsOperations(ctx context.Context, id string) []operation {
+	var (
+		streamARN *string
+		shardIDs  []string
+		iterators []*string
+		writtenAt time.Time
+	)
+
+	return []operation{
+		{
+			method: "DescribeStream",
+			operations: []func() error{
+				func() error {
+					table, err := c.dynamoClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+						TableName: &c.dynamodbTable,
+					})
+					if err != nil {
+						return err
+					}
+
+					streamARN = table.Table.LatestStreamArn
+					if streamARN == nil {
+						return fmt.Errorf("stream is not enabled on table %s", c.dynamodbTable)
+					}
+
+					shardIDs, err = c.openDynamoDBStreamShards(ctx, streamARN)
+					if err != nil {
+						return err
+					}
+
+					return nil
+				},
+			},
+		},
+		{
+			method: "GetShardIterator",
+			operations: []func() error{
+				func() error {
+					iterators = nil
+					for _, shardID := range shardIDs {
+						res, err := c.dynamodbStreamsClient.GetShardIterator(ctx, &dynamodbstreams.GetShardIteratorInput{
+							StreamArn:         streamARN,
+							ShardId:           &shardID,
+							ShardIteratorType: streamstypes.ShardIteratorTypeLatest,
+						})
+						if err != nil {
+							return err
+						}
+						iterators = append(iterators, res.ShardIterator)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "PutStreamItem",
+			operations: []func() error{
+				func() error {
+					writtenAt = time.Now()
+					return c.putDynamoDBProbeItem(ctx, id, "test-data")
+				},
+			},
+		},
+		{
+			method: "GetRecords",
+			operations: []func() error{
+				func() error {
+					if err := c.waitDynamoDBStreamRecord(ctx, iterators, id); err != nil {
+						return err
+					}
+
+					c.deliveryLatency.WithLabelValues("DynamoDB", "PutStreamItem").Observe(time.Since(writtenAt).Seconds())
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// openDynamoDBStreamShards returns the IDs of the open shards of the stream, which receive the new records.
+func (c *checker) openDynamoDBStreamShards(ctx context.Context, streamARN *string) ([]string, error) {
+	var (
+		shardIDs              []string
+		exclusiveStartShardID *string
+	)
+
+	for {
+		res, err := c.dynamodbStreamsClient.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             streamARN,
+			ExclusiveStartShardId: exclusiveStartShardID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, shard := range res.StreamDescription.Shards {
+			if shard.SequenceNumberRange == nil || shard.SequenceNumberRange.EndingSequenceNumber == nil {
+				shardIDs = append(shardIDs, aws.ToString(shard.ShardId))
+			}
+		}
+
+		exclusiveStartShardID = res.StreamDescription.LastEvaluatedShardId
+		if exclusiveStartShardID == nil {
+			break
+		}
+	}
+
+	if len(shardIDs) == 0 {
+		return nil, fmt.Errorf("stream %s has no open shards", aws.ToString(streamARN))
+	}
+
+	return shardIDs, nil
+}
+
+// waitDynamoDBStreamRecord reads the shards from the iterators until the change record of the probe item with the ID shows up,
+// or the streams timeout passes.
+//
+// A shard closed by a split while reading is not followed to its children, and the record is reported as missing
+// if all the shards have been closed.
+func (c *checker) waitDynamoDBStreamRecord(ctx context.Context, iterators []*string, id string) error {
+	timeout := time.After(c.dynamodbStreamsTimeout)
+
+	for {
+		var next []*string
+		for _, iterator := range iterators {
+			res, err := c.dynamodbStreamsClient.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+				ShardIterator: iterator,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, record := range res.Records {
+				if record.Dynamodb != nil && c.isDynamoDBProbeKey(record.Dynamodb.Keys, id) {
+					return nil
+				}
+			}
+
+			if res.NextShardIterator != nil {
+				next = append(next, res.NextShardIterator)
+			}
+		}
+
+		iterators = next
+		if len(iterators) == 0 {
+			return fmt.Errorf("shards were closed before the change record of probe item %s showed up", id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return withStatus(statusTimeout, fmt.Errorf("timed out waiting for the change record of probe item %s", id))
+		case <-time.After(c.dynamodbStreamsPollInterval):
+		}
+	}
+}
+
+// isDynamoDBProbeKey returns true if the keys of a stream record are the ones of the probe item with the ID.
+func (c *checker) isDynamoDBProbeKey(keys map[string]streamstypes.AttributeValue, id string) bool {
+	k := c.dynamodbPartitionKey
+	if !k.unique {
+		k = c.dynamodbSortKey
+	}
+
+	switch v := keys[k.name].(type) {
+	case *streamstypes.AttributeValueMemberS:
+		return v.Value == id
+	case *streamstypes.AttributeValueMemberN:
+		return v.Value == id
+	case *streamstypes.AttributeValueMemberB:
+		return string(v.Value) == id
+	default:
+		return false
+	}
+}
+
+// errDynamoDBReplicationTimeout is returned when the replicated item did not show up within the timeout.
+var errDynamoDBReplicationTimeout = errors.New("timed out waiting for the item to be replicated")
+
+// doCheckDynamoDBReplication writes a timestamped probe item to the global table in the region of the DynamoDB client,
+// and polls the replica regions until the item shows up, to measure the replication lag of every region pair.
+func (c *checker) doCheckDynamoDBReplication(ctx context.Context) {
+	var (
+		id           = c.dynamodbProbeID()
+		sourceRegion = c.dynamoClient.Options().Region
+	)
+
+	putStart := time.Now()
+	err := c.putDynamoDBProbeItem(ctx, id, putStart.Format(time.RFC3339Nano))
+	putDuration := time.Since(putStart)
+	if ctx.Err() == context.Canceled {
+		log.Printf("context is canceled")
+		return
+	} else if err != nil {
+		log.Printf("failed to put replication item, %v", err)
+	}
+	c.observe("DynamoDB", "PutReplicationItem", putDuration, err)
+	if err != nil {
+		return
+	}
+
+	// The deletion is replicated to the replica regions by the global table
+	defer func() {
+		ctx, cancel := cleanupContext()
+		defer cancel()
+
+		if err := c.deleteDynamoDBProbeItem(ctx, id, ""); err != nil {
+			log.Printf("failed to delete replication item %s, %v", id, err)
+		}
+	}()
+
+	written := time.Now()
+
+	var wg sync.WaitGroup
+	for region, client := range c.dynamodbReplicaClients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := c.waitDynamoDBReplication(ctx, client, id)
+			lag := time.Since(written)
+
+			status := "Success"
+			if ctx.Err() == context.Canceled {
+				log.Printf("context is canceled")
+				return
+			} else if errors.Is(err, errDynamoDBReplicationTimeout) {
+				log.Printf("failed to replicate item %s to %s, %v", id, region, err)
+				status = statusTimeout
+				err = withStatus(status, err)
+			} else if err != nil {
+				log.Printf("failed to replicate item %s to %s, %v", id, region, err)
+				status = "Failure"
+			}
+			c.dynamodbReplicationLag.WithLabelValues(sourceRegion, region, status).Observe(lag.Seconds())
+			c.observe("DynamoDB", "Replication/"+region, lag, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// waitDynamoDBReplication polls the replica with eventually consistent reads until the item shows up,
+// or the replication timeout passes.
+func (c *checker) waitDynamoDBReplication(ctx context.Context, client *dynamodb.Client, id string) error {
+	timeout := time.After(c.dynamodbReplicationTimeout)
+
+	for {
+		res, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: &c.dynamodbTable,
+			Key:       c.dynamodbProbeKey(id),
+		})
+		if err != nil {
+			return err
+		}
+		if res.Item != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return errDynamoDBReplicationTimeout
+		case <-time.After(c.dynamodbReplicationPollInterval):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streamstypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBKeyFromEnv(t *testing.T) {
+	k, err := dynamodbKeyFromEnv("DYNAMODB_PARTITION_KEY", "id")
+	require.NoError(t, err)
+	require.NoError(t, k.setFixedValue("DYNAMODB_PARTITION_KEY"))
+	require.Equal(t, &dynamodbKey{name: "id", typ: dynamodbtypes.ScalarAttributeTypeS, value: "test-id"}, k)
+
+	t.Setenv("DYNAMODB_SORT_KEY", "sk")
+	t.Setenv("DYNAMODB_SORT_KEY_TYPE", "N")
+	t.Setenv("DYNAMODB_SORT_KEY_VALUE", "42")
+	k, err = dynamodbKeyFromEnv("DYNAMODB_SORT_KEY", "")
+	require.NoError(t, err)
+	require.NoError(t, k.setFixedValue("DYNAMODB_SORT_KEY"))
+	require.Equal(t, &dynamodbKey{name: "sk", typ: dynamodbtypes.ScalarAttributeTypeN, value: "42"}, k)
+	require.Equal(t, &dynamodbtypes.AttributeValueMemberN{Value: "42"}, k.attributeValue("ignored"))
+
+	t.Setenv("DYNAMODB_SORT_KEY_VALUE", "")
+	k, err = dynamodbKeyFromEnv("DYNAMODB_SORT_KEY", "")
+	require.NoError(t, err)
+	require.NoError(t, k.setFixedValue("DYNAMODB_SORT_KEY"))
+	require.Equal(t, "0", k.value)
+
+	t.Setenv("DYNAMODB_SORT_KEY_VALUE", "test-id")
+	k, err = dynamodbKeyFromEnv("DYNAMODB_SORT_KEY", "")
+	require.NoError(t, err)
+	require.EqualError(t, k.setFixedValue("DYNAMODB_SORT_KEY"), `DYNAMODB_SORT_KEY_VALUE must be a number for the key type N, got "test-id"`)
+
+	// The value of a unique key is never used
+	k.unique = true
+	require.NoError(t, k.setFixedValue("DYNAMODB_SORT_KEY"))
+
+	t.Setenv("DYNAMODB_SORT_KEY_TYPE", "BOOL")
+	_, err = dynamodbKeyFromEnv("DYNAMODB_SORT_KEY", "")
+	require.EqualError(t, err, `DYNAMODB_SORT_KEY_TYPE must be one of S, N, and B, got "BOOL"`)
+}
+
+func TestConfigureDynamoDBNumericPartitionKey(t *testing.T) {
+	t.Setenv("DYNAMODB_PARTITION_KEY_TYPE", "N")
+
+	c := &checker{}
+	require.NoError(t, c.configureDynamoDB(aws.Config{}))
+	require.True(t, c.dynamodbPartitionKey.unique)
+}
+
+func TestDynamoDBProbeIDNumericKey(t *testing.T) {
+	c := &checker{
+		instanceID:             "aws-checker-2",
+		dynamodbProbeKeyPrefix: "aws-checker",
+		dynamodbPartitionKey:   &dynamodbKey{name: "id", typ: dynamodbtypes.ScalarAttributeTypeN, unique: true},
+	}
+
+	id1, id2 := c.dynamodbProbeID(), c.dynamodbProbeID()
+	require.NotEqual(t, id1, id2)
+	require.Regexp(t, `^[0-9]+$`, id1)
+	require.Equal(t, &dynamodbtypes.AttributeValueMemberN{Value: id1}, c.dynamodbPartitionKey.attributeValue(id1))
+
+	c.dynamodbPartitionKey.typ = dynamodbtypes.ScalarAttributeTypeS
+	require.True(t, strings.HasPrefix(c.dynamodbProbeID(), "aws-checker-aws-checker-2-"))
+}
+
+func TestIsDynamoDBProbeKey(t *testing.T) {
+	c := &checker{
+		dynamodbPartitionKey: &dynamodbKey{name: "id", typ: dynamodbtypes.ScalarAttributeTypeN, unique: true},
+	}
+
+	require.True(t, c.isDynamoDBProbeKey(map[string]streamstypes.AttributeValue{
+		"id": &streamstypes.AttributeValueMemberN{Value: "1700000000000000000001"},
+	}, "1700000000000000000001"))
+	require.False(t, c.isDynamoDBProbeKey(map[string]streamstypes.AttributeValue{
+		"id": &streamstypes.AttributeValueMemberN{Value: "1700000000000000000002"},
+	}, "1700000000000000000001"))
+
+	c.dynamodbPartitionKey = &dynamodbKey{name: "pk", typ: dynamodbtypes.ScalarAttributeTypeS, value: "test-id"}
+	c.dynamodbSortKey = &dynamodbKey{name: "sk", typ: dynamodbtypes.ScalarAttributeTypeS, unique: true}
+	require.True(t, c.isDynamoDBProbeKey(map[string]streamstypes.AttributeValue{
+		"pk": &streamstypes.AttributeValueMemberS{Value: "test-id"},
+		"sk": &streamstypes.AttributeValueMemberS{Value: "aws-checker-1"},
+	}, "aws-checker-1"))
+	require.False(t, c.isDynamoDBProbeKey(map[string]streamstypes.AttributeValue{
+		"pk": &streamstypes.AttributeValueMemberS{Value: "aws-checker-1"},
+	}, "aws-checker-1"))
+}
+
+func TestParseDynamoDBItemAttributes(t *testing.T) {
+	attrs, err := parseDynamoDBItemAttributes(`{"owner": "aws-checker", "ttl": 1700000000, "probe": true}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]dynamodbtypes.AttributeValue{
+		"owner": &dynamodbtypes.AttributeValueMemberS{Value: "aws-checker"},
+		"ttl":   &dynamodbtypes.AttributeValueMemberN{Value: "1700000000"},
+		"probe": &dynamodbtypes.AttributeValueMemberBOOL{Value: true},
+	}, attrs)
+
+	_, err = parseDynamoDBItemAttributes(`{"tags": ["a"]}`)
+	require.EqualError(t, err, `attribute "tags" must be a string, number, or boolean`)
+}
+
+func TestDynamoDBWriteRejected(t *testing.T) {
+	responseError := func(status int, err error) error {
+		return &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      err,
+			},
+		}
+	}
+
+	require.True(t, dynamodbWriteRejected(responseError(400, errors.New("AccessDeniedException"))))
+	require.True(t, dynamodbWriteRejected(responseError(400, &dynamodbtypes.ResourceNotFoundException{})))
+	require.False(t, dynamodbWriteRejected(responseError(400, &dynamodbtypes.ConditionalCheckFailedException{})))
+	require.False(t, dynamodbWriteRejected(responseError(500, &dynamodbtypes.InternalServerError{})))
+	require.False(t, dynamodbWriteRejected(context.DeadlineExceeded))
+	require.False(t, dynamodbWriteRejected(nil))
+}
+
+func TestTrackDynamoDBProbeItems(t *testing.T) {
+	c := &checker{dynamodbProbeItems: make(map[string]time.Time)}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.trackDynamoDBProbeItemsAt(now, "first")
+	for i := 0; i < dynamodbMaxProbeItems; i++ {
+		c.trackDynamoDBProbeItemsAt(now.Add(time.Duration(i+1)*time.Second), fmt.Sprintf("item-%d", i))
+	}
+	require.Len(t, c.dynamodbProbeItems, dynamodbMaxProbeItems)
+	require.NotContains(t, c.dynamodbProbeItems, "first")
+
+	c.untrackDynamoDBProbeItems("item-0", "item-1")
+	require.Len(t, c.dynamodbProbeItems, dynamodbMaxProbeItems-2)
+	require.NotContains(t, c.dynamodbProbeItems, "item-0")
+}
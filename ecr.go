@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// ecrManifestMediaTypes are the media types of the image manifests and indexes to accept,
+// so that multi-platform images are returned as they are.
+var ecrManifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// configureECR configures the ECR check from the environment variables.
+func (c *checker) configureECR(cfg aws.Config) error {
+	c.ecrRepositoryName = os.Getenv("ECR_REPOSITORY_NAME")
+	if c.ecrRepositoryName == "" {
+		return nil
+	}
+
+	c.ecrClient = ecr.NewFromConfig(cfg, c.ecrOpts...)
+
+	c.ecrImageTag = os.Getenv("ECR_IMAGE_TAG")
+	if c.ecrImageTag == "" {
+		c.ecrImageTag = "latest"
+	}
+
+	if v := os.Getenv("ECR_REGISTRY_ID"); v != "" {
+		c.ecrRegistryID = &v
+	}
+
+	var err error
+	c.ecrManifestCheck, err = envBool("ECR_MANIFEST_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.ecrRegistryEndpoint = strings.TrimSuffix(os.Getenv("ECR_REGISTRY_ENDPOINT"), "/")
+	if c.ecrRegistryEndpoint != "" && !c.ecrManifestCheck {
+		return fmt.Errorf("ECR_REGISTRY_ENDPOINT requires ECR_MANIFEST_CHECK")
+	}
+
+	return nil
+}
+
+// doCheckECR gets an authorization token and the image of the repository,
+// and optionally sends a HEAD request for the image manifest to the registry with the token,
+// as container runtimes do to pull the image.
+func (c *checker) doCheckECR(ctx context.Context) {
+	var (
+		authorizationToken string
+		proxyEndpoint      string
+		imageDigest        string
+	)
+
+	imageID := ecrtypes.ImageIdentifier{ImageTag: &c.ecrImageTag}
+
+	ops := []operation{
+		{
+			method: "GetAuthorizationToken",
+			operations: []func() error{
+				func() error {
+					in := &ecr.GetAuthorizationTokenInput{}
+					if c.ecrRegistryID != nil {
+						in.RegistryIds = []string{*c.ecrRegistryID}
+					}
+
+					res, err := c.ecrClient.GetAuthorizationToken(ctx, in)
+					if err != nil {
+						return err
+					}
+
+					if len(res.AuthorizationData) == 0 {
+						return fmt.Errorf("no authorization data returned")
+					}
+
+					// Never log the token as it grants pulling and pushing images
+					authorizationToken = aws.ToString(res.AuthorizationData[0].AuthorizationToken)
+					proxyEndpoint = aws.ToString(res.AuthorizationData[0].ProxyEndpoint)
+					return nil
+				},
+			},
+		},
+		{
+			method: "DescribeImages",
+			operations: []func() error{
+				func() error {
+					res, err := c.ecrClient.DescribeImages(ctx, &ecr.DescribeImagesInput{
+						RepositoryName: &c.ecrRepositoryName,
+						RegistryId:     c.ecrRegistryID,
+						ImageIds:       []ecrtypes.ImageIdentifier{imageID},
+					})
+					if err != nil {
+						return err
+					}
+
+					if len(res.ImageDetails) == 0 {
+						return fmt.Errorf("image %s:%s not found", c.ecrRepositoryName, c.ecrImageTag)
+					}
+
+					imageDigest = aws.ToString(res.ImageDetails[0].ImageDigest)
+					return nil
+				},
+			},
+		},
+		{
+			method: "BatchGetImage",
+			operations: []func() error{
+				func() error {
+					res, err := c.ecrClient.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+						RepositoryName:     &c.ecrRepositoryName,
+						RegistryId:         c.ecrRegistryID,
+						ImageIds:           []ecrtypes.ImageIdentifier{imageID},
+						AcceptedMediaTypes: ecrManifestMediaTypes,
+					})
+					if err != nil {
+						return err
+					}
+
+					if len(res.Failures) > 0 {
+						f := res.Failures[0]
+						return fmt.Errorf("failed to get image %s:%s, %s: %s", c.ecrRepositoryName, c.ecrImageTag, f.FailureCode, aws.ToString(f.FailureReason))
+					}
+					if len(res.Images) == 0 || aws.ToString(res.Images[0].ImageManifest) == "" {
+						return fmt.Errorf("no manifest returned for image %s:%s", c.ecrRepositoryName, c.ecrImageTag)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	if c.ecrManifestCheck {
+		ops = append(ops, operation{
+			method: "HeadManifest",
+			operations: []func() error{
+				func() error {
+					if authorizationToken == "" {
+						return errSkipOperation
+					}
+
+					endpoint := c.ecrRegistryEndpoint
+					if endpoint == "" {
+						endpoint = proxyEndpoint
+					}
+
+					return c.headECRManifest(ctx, ecrManifestURL(endpoint, c.ecrRepositoryName, c.ecrImageTag), authorizationToken, imageDigest)
+				},
+			},
+		})
+	}
+
+	c.doCheckService(ctx, "ECR", ops)
+}
+
+// headECRManifest sends a HEAD request for the image manifest to the registry v2 API at url,
+// and compares the digest of the manifest to the expected one unless it is empty.
+func (c *checker) headECRManifest(ctx context.Context, url, authorizationToken, digest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+
+	// The token is the base64-encoded "AWS:<password>" for the basic authentication
+	req.Header.Set("Authorization", "Basic "+authorizationToken)
+	req.Header.Set("Accept", strings.Join(ecrManifestMediaTypes, ", "))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("HEAD %s failed with status code %d", url, res.StatusCode)
+	}
+
+	if got := res.Header.Get("Docker-Content-Digest"); digest != "" && got != digest {
+		return withStatus(statusCorruptData, fmt.Errorf("manifest %s has digest %s, expected %s", url, got, digest))
+	}
+	return nil
+}
+
+// ecrManifestURL returns the registry v2 API URL of the manifest of the image in the repository.
+// The endpoint is like https://123456789012.dkr.ecr.ap-northeast-1.amazonaws.com, with or without the scheme.
+func ecrManifestURL(endpoint, repository, reference string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	return strings.TrimSuffix(endpoint, "/") + "/v2/" + repository + "/manifests/" + reference
+}
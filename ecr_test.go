@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestECRManifestURL(t *testing.T) {
+	require.Equal(t,
+		"https://123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/v2/myapp/manifests/latest",
+		ecrManifestURL("https://123456789012.dkr.ecr.ap-northeast-1.amazonaws.com", "myapp", "latest"),
+	)
+	require.Equal(t,
+		"https://123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/v2/team/myapp/manifests/v1.2.3",
+		ecrManifestURL("123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/", "team/myapp", "v1.2.3"),
+	)
+	require.Equal(t,
+		"http://localhost:5000/v2/myapp/manifests/latest",
+		ecrManifestURL("http://localhost:5000", "myapp", "latest"),
+	)
+}
+
+func TestHeadECRManifest(t *testing.T) {
+	const (
+		token  = "QVdTOnNlY3JldA=="
+		digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	)
+
+	var (
+		status    int
+		gotDigest string
+	)
+
+	// The handler runs on another goroutine, so the requests are asserted by the test goroutine
+	requests := make(chan *http.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Clone(context.Background())
+
+		w.Header().Set("Docker-Content-Digest", gotDigest)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	url := ecrManifestURL(srv.URL, "team/myapp", "latest")
+
+	status, gotDigest = http.StatusOK, digest
+	require.NoError(t, c.headECRManifest(context.Background(), url, token, digest))
+
+	r := <-requests
+	require.Equal(t, http.MethodHead, r.Method)
+	require.Equal(t, "/v2/team/myapp/manifests/latest", r.URL.Path)
+	require.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+
+	// The token is sent only in the Authorization header
+	require.Equal(t, "Basic "+token, r.Header.Get("Authorization"))
+	require.NotContains(t, r.URL.String(), token)
+	for name, values := range r.Header {
+		if name != "Authorization" {
+			require.NotContains(t, strings.Join(values, ","), token, name)
+		}
+	}
+
+	// The digest is not compared when DescribeImages has not returned it
+	require.NoError(t, c.headECRManifest(context.Background(), url, token, ""))
+	<-requests
+
+	status, gotDigest = http.StatusOK, "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	err := c.headECRManifest(context.Background(), url, token, digest)
+	<-requests
+	require.Equal(t, []string{statusCorruptData}, errorCodes(err))
+
+	status, gotDigest = http.StatusUnauthorized, ""
+	err = c.headECRManifest(context.Background(), url, token, digest)
+	<-requests
+	require.ErrorContains(t, err, "status code 401")
+	require.NotContains(t, err.Error(), token)
+}
+
+func TestDoCheckECR(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	var (
+		registryURL    string
+		manifestDigest string
+	)
+
+	// Serves both of the ECR API and the registry
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Docker-Content-Digest", manifestDigest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken":
+			_, _ = fmt.Fprintf(w, `{"authorizationData": [{"authorizationToken": "QVdTOnNlY3JldA==", "proxyEndpoint": %q}]}`, registryURL)
+		case "AmazonEC2ContainerRegistry_V20150921.DescribeImages":
+			_, _ = fmt.Fprintf(w, `{"imageDetails": [{"repositoryName": "team/myapp", "imageDigest": %q, "imageTags": ["latest"]}]}`, digest)
+		case "AmazonEC2ContainerRegistry_V20150921.BatchGetImage":
+			_, _ = fmt.Fprintf(w, `{"images": [{"repositoryName": "team/myapp", "imageId": {"imageDigest": %q, "imageTag": "latest"}, "imageManifest": "{}"}], "failures": []}`, digest)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"__type": "UnknownOperationException", "message": %q}`, target)
+		}
+	}))
+	defer srv.Close()
+	registryURL = srv.URL
+
+	t.Setenv("ECR_REPOSITORY_NAME", "team/myapp")
+	t.Setenv("ECR_MANIFEST_CHECK", "true")
+
+	run := func(t *testing.T) map[string]string {
+		c := newTestChecker()
+		require.NoError(t, c.configureECR(testAWSConfig(srv.URL)))
+
+		c.doCheckECR(context.Background())
+
+		statuses := make(map[string]string)
+		for _, op := range c.status.operations() {
+			require.Equal(t, "ECR", op.Service)
+			statuses[op.Method] = op.Status
+		}
+		return statuses
+	}
+
+	t.Run("success", func(t *testing.T) {
+		manifestDigest = digest
+
+		require.Equal(t, map[string]string{
+			"GetAuthorizationToken": "Success",
+			"DescribeImages":        "Success",
+			"BatchGetImage":         "Success",
+			"HeadManifest":          "Success",
+		}, run(t))
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		manifestDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+		require.Equal(t, map[string]string{
+			"GetAuthorizationToken": "Success",
+			"DescribeImages":        "Success",
+			"BatchGetImage":         "Success",
+			"HeadManifest":          statusCorruptData,
+		}, run(t))
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// statusUnexpectedSuccess is the status of a step that was expected to fail but succeeded.
+const statusUnexpectedSuccess = "UnexpectedSuccess"
+
+// expectation is the expected outcome of a step or an operation.
+// The zero value expects it to succeed.
+type expectation struct {
+	// errorCode is the error code of the API error it is expected to fail with, like ConditionalCheckFailedException.
+	errorCode string
+	// httpStatus is the HTTP status code of the error response it is expected to fail with, like 403.
+	httpStatus int
+}
+
+// expectedOutcome is the expectation configured for an operation.
+type expectedOutcome struct {
+	service string
+	// method is the method the expectation applies to, or "*" for all methods of the service.
+	method string
+
+	expectation
+}
+
+func (e expectation) String() string {
+	switch {
+	case e.errorCode != "":
+		return e.errorCode
+	case e.httpStatus != 0:
+		return fmt.Sprintf("HTTP %d", e.httpStatus)
+	default:
+		return "Success"
+	}
+}
+
+// check returns nil if err is the expected outcome, and the error to be recorded otherwise.
+func (e expectation) check(err error) error {
+	if e == (expectation{}) {
+		return err
+	}
+
+	if err == nil {
+		return withStatus(statusUnexpectedSuccess, fmt.Errorf("expected %s, but succeeded", e))
+	}
+
+	var apiErr smithy.APIError
+	if e.errorCode != "" && errors.As(err, &apiErr) && apiErr.ErrorCode() == e.errorCode {
+		return nil
+	}
+
+	var respErr *awshttp.ResponseError
+	if e.httpStatus != 0 && errors.As(err, &respErr) && respErr.HTTPStatusCode() == e.httpStatus {
+		return nil
+	}
+
+	return fmt.Errorf("expected %s, got %w", e, err)
+}
+
+// expectError returns the step that succeeds only if fn fails with the API error code.
+func expectError(code string, fn func() error) func() error {
+	e := expectation{errorCode: code}
+
+	return func() error {
+		return e.check(fn())
+	}
+}
+
+// parseExpectation parses an expected outcome, which is `Success`, an API error code like `AccessDenied`,
+// or an HTTP status code like `403`.
+func parseExpectation(v string) (expectation, error) {
+	switch {
+	case v == "":
+		return expectation{}, fmt.Errorf("empty outcome")
+	case v == "Success":
+		return expectation{}, nil
+	}
+
+	if status, err := strconv.Atoi(v); err == nil {
+		if status < 400 || status > 599 {
+			return expectation{}, fmt.Errorf("HTTP status %d must be an error status between 400 and 599", status)
+		}
+		return expectation{httpStatus: status}, nil
+	}
+
+	return expectation{errorCode: v}, nil
+}
+
+// newExpectedOutcomesFromEnv returns the expected outcomes configured by EXPECTED_OUTCOMES.
+func newExpectedOutcomesFromEnv() ([]expectedOutcome, error) {
+	v := os.Getenv("EXPECTED_OUTCOMES")
+	if v == "" {
+		return nil, nil
+	}
+
+	outcomes, err := parseExpectedOutcomes(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXPECTED_OUTCOMES %q, %v", v, err)
+	}
+
+	return outcomes, nil
+}
+
+// parseExpectedOutcomes parses comma-separated expected outcomes like `S3/GetObject=AccessDenied,S3/*=403`.
+func parseExpectedOutcomes(v string) ([]expectedOutcome, error) {
+	var outcomes []expectedOutcome
+
+	for _, entry := range strings.Split(v, ",") {
+		op, outcome, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("missing outcome in %q", entry)
+		}
+
+		service, method, ok := strings.Cut(op, "/")
+		if !ok || service == "" || method == "" {
+			return nil, fmt.Errorf("operation %q must be in the form of SERVICE/METHOD", op)
+		}
+
+		e, err := parseExpectation(outcome)
+		if err != nil {
+			return nil, fmt.Errorf("invalid outcome %q, %v", outcome, err)
+		}
+
+		outcomes = append(outcomes, expectedOutcome{service: service, method: method, expectation: e})
+	}
+
+	return outcomes, nil
+}
+
+// outcome returns nil if err is the expected outcome of the operation, and the error to be recorded otherwise.
+// The expectation for the method takes precedence over the one for all methods of the service.
+func (c *checker) outcome(service, method string, err error) error {
+	var (
+		e     expectation
+		found bool
+	)
+
+	for _, o := range c.expectedOutcomes {
+		if o.service != service {
+			continue
+		}
+		if o.method == method {
+			return o.check(err)
+		}
+		if o.method == "*" && !found {
+			e, found = o.expectation, true
+		}
+	}
+
+	return e.check(err)
+}
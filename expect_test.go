@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectError(t *testing.T) {
+	condErr := fmt.Errorf("operation error DynamoDB: PutItem, %w", &dynamodbtypes.ConditionalCheckFailedException{})
+
+	step := func(err error) func() error {
+		return expectError("ConditionalCheckFailedException", func() error { return err })
+	}
+
+	require.NoError(t, step(condErr)())
+
+	err := step(nil)()
+	var se *statusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, statusUnexpectedSuccess, se.status)
+	require.EqualError(t, err, "expected ConditionalCheckFailedException, but succeeded")
+
+	err = step(errors.New("connection reset"))()
+	require.False(t, errors.As(err, &se))
+	require.EqualError(t, err, "expected ConditionalCheckFailedException, got connection reset")
+
+	require.NoError(t, expectation{}.check(nil))
+	require.Equal(t, condErr, expectation{}.check(condErr))
+}
+
+func TestParseExpectedOutcomes(t *testing.T) {
+	outcomes, err := parseExpectedOutcomes("S3/GetObject=AccessDenied, S3/*=403,SQS/ReceiveMessage=Success")
+	require.NoError(t, err)
+	require.Equal(t, []expectedOutcome{
+		{service: "S3", method: "GetObject", expectation: expectation{errorCode: "AccessDenied"}},
+		{service: "S3", method: "*", expectation: expectation{httpStatus: 403}},
+		{service: "SQS", method: "ReceiveMessage"},
+	}, outcomes)
+
+	_, err = parseExpectedOutcomes("S3/GetObject")
+	require.EqualError(t, err, `missing outcome in "S3/GetObject"`)
+
+	_, err = parseExpectedOutcomes("GetObject=AccessDenied")
+	require.EqualError(t, err, `operation "GetObject" must be in the form of SERVICE/METHOD`)
+
+	_, err = parseExpectedOutcomes("S3/GetObject=200")
+	require.EqualError(t, err, `invalid outcome "200", HTTP status 200 must be an error status between 400 and 599`)
+}
+
+func TestOutcome(t *testing.T) {
+	c := &checker{
+		expectedOutcomes: []expectedOutcome{
+			{service: "S3", method: "*", expectation: expectation{httpStatus: 403}},
+			{service: "S3", method: "HeadObject", expectation: expectation{errorCode: "NotFound"}},
+		},
+	}
+
+	forbidden := &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: 403}},
+			Err:      errors.New("forbidden"),
+		},
+	}
+
+	require.NoError(t, c.outcome("S3", "GetObject", forbidden))
+	require.EqualError(t, c.outcome("S3", "GetObject", nil), "expected HTTP 403, but succeeded")
+
+	require.NoError(t, c.outcome("S3", "HeadObject", &s3types.NotFound{}))
+	require.Error(t, c.outcome("S3", "HeadObject", forbidden))
+
+	require.NoError(t, c.outcome("SQS", "ReceiveMessage", nil))
+	require.Equal(t, forbidden, c.outcome("SQS", "ReceiveMessage", forbidden))
+}
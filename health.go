@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	healthHealthy   = "healthy"
+	healthDegraded  = "degraded"
+	healthUnhealthy = "unhealthy"
+)
+
+// healthValues are the values of the aws_health_state gauge.
+var healthValues = map[string]float64{
+	healthHealthy:   0,
+	healthDegraded:  1,
+	healthUnhealthy: 2,
+}
+
+// health is the state machine that turns the results of every operation into a health state.
+//
+// An operation becomes degraded on the first failure, unhealthy after fall consecutive failures,
+// and healthy again after rise consecutive successes.
+//
+// An operation is flapping when the ratio of pass/fail changes within the last flapWindow results
+// reaches flapThreshold, and stops flapping once the ratio goes below half of flapThreshold.
+// A flapping operation is never healthy, so that it does not flip between healthy and unhealthy.
+type health struct {
+	rise, fall    int
+	flapWindow    int
+	flapThreshold float64
+
+	// listeners are called with every result and the health of the operation after the result.
+	listeners []healthListener
+
+	mu  sync.Mutex
+	ops map[operationKey]*healthState
+
+	stateDesc    *prometheus.Desc
+	flappingDesc *prometheus.Desc
+}
+
+type healthListener interface {
+	update(r result, h healthStatus)
+}
+
+type healthState struct {
+	healthStatus
+
+	// history is a ring buffer of whether the last results were successful.
+	history []bool
+	next    int
+	full    bool
+}
+
+// healthStatus is the health of an operation.
+type healthStatus struct {
+	state                string
+	flapping             bool
+	consecutiveFailures  int
+	consecutiveSuccesses int
+}
+
+// newHealthFromEnv returns the health configured by the HEALTH_* environment variables.
+func newHealthFromEnv() (*health, error) {
+	rise, err := envInt("HEALTH_RISE_THRESHOLD", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	fall, err := envInt("HEALTH_FALL_THRESHOLD", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	flapWindow, err := envInt("HEALTH_FLAP_WINDOW", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	flapThreshold := 0.5
+	if v := os.Getenv("HEALTH_FLAP_THRESHOLD"); v != "" {
+		flapThreshold, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HEALTH_FLAP_THRESHOLD %q, %v", v, err)
+		}
+	}
+
+	return newHealth(rise, fall, flapWindow, flapThreshold)
+}
+
+func newHealth(rise, fall, flapWindow int, flapThreshold float64) (*health, error) {
+	if rise < 1 {
+		return nil, fmt.Errorf("rise threshold must be at least 1, got %d", rise)
+	}
+
+	if fall < 1 {
+		return nil, fmt.Errorf("fall threshold must be at least 1, got %d", fall)
+	}
+
+	if flapWindow < 2 {
+		return nil, fmt.Errorf("flap window must be at least 2, got %d", flapWindow)
+	}
+
+	if flapThreshold <= 0 || flapThreshold > 1 {
+		return nil, fmt.Errorf("flap threshold must be greater than 0 and at most 1, got %v", flapThreshold)
+	}
+
+	return &health{
+		rise:          rise,
+		fall:          fall,
+		flapWindow:    flapWindow,
+		flapThreshold: flapThreshold,
+		ops:           make(map[operationKey]*healthState),
+		stateDesc: prometheus.NewDesc(
+			"aws_health_state",
+			"The health state of the operation. 0 is healthy, 1 is degraded, and 2 is unhealthy.",
+			[]string{"service", "method"}, nil,
+		),
+		flappingDesc: prometheus.NewDesc(
+			"aws_health_flapping",
+			"1 if the operation is flapping between success and failure, 0 otherwise.",
+			[]string{"service", "method"}, nil,
+		),
+	}, nil
+}
+
+func (h *health) observe(r result) {
+	status := h.update(r)
+
+	for _, l := range h.listeners {
+		l.update(r, status)
+	}
+}
+
+func (h *health) update(r result) healthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := operationKey{service: r.service, method: r.method}
+	s, ok := h.ops[key]
+	if !ok {
+		s = &healthState{
+			healthStatus: healthStatus{state: healthHealthy},
+			history:      make([]bool, h.flapWindow),
+		}
+		h.ops[key] = s
+	}
+
+	ok = r.err == nil
+
+	s.history[s.next] = ok
+	s.next = (s.next + 1) % len(s.history)
+	if s.next == 0 {
+		s.full = true
+	}
+
+	if s.full {
+		ratio := s.changeRatio()
+		if ratio >= h.flapThreshold {
+			s.flapping = true
+		} else if ratio < h.flapThreshold/2 {
+			s.flapping = false
+		}
+	}
+
+	if ok {
+		s.consecutiveSuccesses++
+		s.consecutiveFailures = 0
+		if s.consecutiveSuccesses >= h.rise {
+			s.state = healthHealthy
+		}
+	} else {
+		s.consecutiveFailures++
+		s.consecutiveSuccesses = 0
+		if s.consecutiveFailures >= h.fall {
+			s.state = healthUnhealthy
+		} else if s.state == healthHealthy {
+			s.state = healthDegraded
+		}
+	}
+
+	if s.flapping && s.state == healthHealthy {
+		s.state = healthDegraded
+	}
+
+	return s.healthStatus
+}
+
+// changeRatio returns the ratio of pass/fail changes between consecutive results in the history.
+func (s *healthState) changeRatio() float64 {
+	var changes int
+
+	n := len(s.history)
+	for i := 1; i < n; i++ {
+		// s.next is the oldest entry when the history is full
+		prev := s.history[(s.next+i-1)%n]
+		cur := s.history[(s.next+i)%n]
+		if prev != cur {
+			changes++
+		}
+	}
+
+	return float64(changes) / float64(n-1)
+}
+
+// status returns the health of the operation, and false if the operation has not been checked yet.
+func (h *health) status(service, method string) (healthStatus, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.ops[operationKey{service: service, method: method}]
+	if !ok {
+		return healthStatus{}, false
+	}
+
+	return s.healthStatus, true
+}
+
+// unhealthy returns the operations that are unhealthy.
+func (h *health) unhealthy() []operationKey {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var keys []operationKey
+	for key, s := range h.ops {
+		if s.state == healthUnhealthy {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].method < keys[j].method
+	})
+
+	return keys
+}
+
+func (h *health) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.stateDesc
+	ch <- h.flappingDesc
+}
+
+func (h *health) Collect(ch chan<- prometheus.Metric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.ops {
+		var flapping float64
+		if s.flapping {
+			flapping = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(h.stateDesc, prometheus.GaugeValue, healthValues[s.state], key.service, key.method)
+		ch <- prometheus.MustNewConstMetric(h.flappingDesc, prometheus.GaugeValue, flapping, key.service, key.method)
+	}
+}
+
+// readinessHandler responds with 503 while any operation is unhealthy, and 200 otherwise.
+func (h *health) readinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unhealthy := h.unhealthy()
+		if len(unhealthy) == 0 {
+			fmt.Fprintln(w, "ok")
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		for _, key := range unhealthy {
+			fmt.Fprintf(w, "%s %s is unhealthy\n", key.service, key.method)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	h, err := newHealth(2, 3, 4, 0.5)
+	require.NoError(t, err)
+
+	observe := func(err error) healthStatus {
+		h.observe(result{service: "SQS", method: "ReceiveMessage", err: err})
+		s, ok := h.status("SQS", "ReceiveMessage")
+		require.True(t, ok)
+		return s
+	}
+
+	fail := errors.New("timeout")
+
+	require.Equal(t, healthHealthy, observe(nil).state)
+	require.Equal(t, healthDegraded, observe(fail).state)
+	require.Equal(t, healthDegraded, observe(fail).state)
+
+	s := observe(fail)
+	require.Equal(t, healthUnhealthy, s.state)
+	require.Equal(t, 3, s.consecutiveFailures)
+	require.False(t, s.flapping)
+
+	requireReadiness(t, h, http.StatusServiceUnavailable, "SQS ReceiveMessage is unhealthy\n")
+
+	require.Equal(t, healthUnhealthy, observe(nil).state, "a single success must not recover with a rise threshold of 2")
+	require.Equal(t, healthHealthy, observe(nil).state)
+
+	requireReadiness(t, h, http.StatusOK, "ok\n")
+
+	// The last 4 results are now pass, fail, pass, fail, which is 3 changes out of 3.
+	observe(fail)
+	observe(nil)
+	s = observe(fail)
+	require.True(t, s.flapping)
+	require.Equal(t, healthDegraded, s.state)
+
+	observe(nil)
+	s = observe(nil)
+	require.True(t, s.flapping)
+	require.Equal(t, healthDegraded, s.state, "a flapping operation must not become healthy")
+
+	observe(nil)
+	s = observe(nil)
+	require.False(t, s.flapping)
+	require.Equal(t, healthHealthy, s.state)
+}
+
+func requireReadiness(t *testing.T, h *health, code int, body string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.readinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	res := rec.Result()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, code, res.StatusCode)
+	require.Equal(t, body, string(b))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// configureKinesis configures the Kinesis check from the environment variables.
+func (c *checker) configureKinesis(cfg aws.Config) error {
+	var err error
+
+	c.kinesisStreamName = os.Getenv("KINESIS_STREAM_NAME")
+	if c.kinesisStreamName == "" {
+		return nil
+	}
+
+	c.kinesisClient = kinesis.NewFromConfig(cfg, c.kinesisOpts...)
+
+	c.kinesisReadTimeout, err = envDuration("KINESIS_READ_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	// Every shard supports up to 5 GetRecords calls per second
+	c.kinesisPollInterval, err = envDuration("KINESIS_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// doCheckKinesis puts a probe record to the stream, and reads it back from its shard.
+func (c *checker) doCheckKinesis(ctx context.Context) {
+	var (
+		probeID        = c.probeID()
+		putAt          time.Time
+		shardID        *string
+		sequenceNumber *string
+		shardIterator  *string
+	)
+
+	c.doCheckService(ctx, "Kinesis", []operation{
+		{
+			method: "DescribeStreamSummary",
+			operations: []func() error{
+				func() error {
+					res, err := c.kinesisClient.DescribeStreamSummary(ctx, &kinesis.DescribeStreamSummaryInput{
+						StreamName: &c.kinesisStreamName,
+					})
+					if err != nil {
+						return err
+					}
+
+					// An updating stream accepts reads and writes
+					if s := res.StreamDescriptionSummary.StreamStatus; s != kinesistypes.StreamStatusActive && s != kinesistypes.StreamStatusUpdating {
+						return fmt.Errorf("stream %s is %s", c.kinesisStreamName, s)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "PutRecord",
+			operations: []func() error{
+				func() error {
+					putAt = time.Now()
+					res, err := c.kinesisClient.PutRecord(ctx, &kinesis.PutRecordInput{
+						StreamName:   &c.kinesisStreamName,
+						PartitionKey: aws.String(probeID),
+						Data:         []byte(probeID),
+					})
+					if err != nil {
+						return err
+					}
+
+					shardID = res.ShardId
+					sequenceNumber = res.SequenceNumber
+					return nil
+				},
+			},
+		},
+		{
+			method: "GetShardIterator",
+			operations: []func() error{
+				func() error {
+					if sequenceNumber == nil {
+						return errSkipOperation
+					}
+
+					res, err := c.kinesisClient.GetShardIterator(ctx, &kinesis.GetShardIteratorInput{
+						StreamName:             &c.kinesisStreamName,
+						ShardId:                shardID,
+						ShardIteratorType:      kinesistypes.ShardIteratorTypeAtSequenceNumber,
+						StartingSequenceNumber: sequenceNumber,
+					})
+					if err != nil {
+						return err
+					}
+
+					shardIterator = res.ShardIterator
+					return nil
+				},
+			},
+		},
+		{
+			method: "GetRecords",
+			operations: []func() error{
+				func() error {
+					if shardIterator == nil {
+						return errSkipOperation
+					}
+
+					record, err := c.waitKinesisRecord(ctx, shardIterator, aws.ToString(sequenceNumber))
+					if err != nil {
+						return err
+					}
+
+					c.deliveryLatency.WithLabelValues("Kinesis", "PutRecord").Observe(time.Since(putAt).Seconds())
+
+					if !bytes.Equal(record.Data, []byte(probeID)) {
+						return withStatus(statusCorruptData, fmt.Errorf("probe record %s has unexpected data %q", probeID, record.Data))
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+// waitKinesisRecord reads the shard from the iterator until the record with the sequence number shows up,
+// or the read timeout passes.
+func (c *checker) waitKinesisRecord(ctx context.Context, shardIterator *string, sequenceNumber string) (kinesistypes.Record, error) {
+	timeout := time.After(c.kinesisReadTimeout)
+
+	for {
+		res, err := c.kinesisClient.GetRecords(ctx, &kinesis.GetRecordsInput{
+			ShardIterator: shardIterator,
+		})
+		if err != nil {
+			return kinesistypes.Record{}, err
+		}
+
+		for _, record := range res.Records {
+			if aws.ToString(record.SequenceNumber) == sequenceNumber {
+				return record, nil
+			}
+		}
+
+		shardIterator = res.NextShardIterator
+		if shardIterator == nil {
+			return kinesistypes.Record{}, fmt.Errorf("shard was closed before the record %s showed up", sequenceNumber)
+		}
+
+		select {
+		case <-ctx.Done():
+			return kinesistypes.Record{}, ctx.Err()
+		case <-timeout:
+			return kinesistypes.Record{}, withStatus(statusTimeout, fmt.Errorf("timed out waiting for the record %s", sequenceNumber))
+		case <-time.After(c.kinesisPollInterval):
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// configureKMS configures the KMS check from the environment variables.
+func (c *checker) configureKMS(cfg aws.Config) error {
+	c.kmsRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aws_kms_request_duration_seconds",
+			Help:    "Time spent in KMS requests with the key, by method.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+		},
+		[]string{"key", "method"},
+	)
+
+	c.kmsKeyID = os.Getenv("KMS_KEY_ID")
+	if c.kmsKeyID == "" {
+		return nil
+	}
+
+	c.kmsClient = kms.NewFromConfig(cfg, c.kmsOpts...)
+
+	var err error
+	c.kmsGenerateDataKeyCheck, err = envBool("KMS_GENERATE_DATA_KEY_CHECK")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// doCheckKMS encrypts a probe payload with the key and decrypts it back,
+// and optionally generates a data key and decrypts its encrypted copy,
+// as S3 and DynamoDB do with customer managed keys.
+func (c *checker) doCheckKMS(ctx context.Context) {
+	var (
+		plaintext      = []byte(c.probeID())
+		ciphertextBlob []byte
+	)
+
+	ops := []operation{
+		{
+			method: "Encrypt",
+			operations: []func() error{
+				func() error {
+					var res *kms.EncryptOutput
+					err := c.timeKMS("Encrypt", func() (err error) {
+						res, err = c.kmsClient.Encrypt(ctx, &kms.EncryptInput{
+							KeyId:     &c.kmsKeyID,
+							Plaintext: plaintext,
+						})
+						return err
+					})
+					if err != nil {
+						return err
+					}
+
+					ciphertextBlob = res.CiphertextBlob
+					return nil
+				},
+			},
+		},
+		{
+			method: "Decrypt",
+			operations: []func() error{
+				func() error {
+					if ciphertextBlob == nil {
+						return errSkipOperation
+					}
+
+					return c.decryptKMS(ctx, ciphertextBlob, plaintext)
+				},
+			},
+		},
+	}
+
+	if c.kmsGenerateDataKeyCheck {
+		ops = append(ops, operation{
+			method: "GenerateDataKey",
+			operations: []func() error{
+				func() error {
+					var res *kms.GenerateDataKeyOutput
+					err := c.timeKMS("GenerateDataKey", func() (err error) {
+						res, err = c.kmsClient.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+							KeyId:   &c.kmsKeyID,
+							KeySpec: kmstypes.DataKeySpecAes256,
+						})
+						return err
+					})
+					if err != nil {
+						return err
+					}
+
+					return c.decryptKMS(ctx, res.CiphertextBlob, res.Plaintext)
+				},
+			},
+		})
+	}
+
+	c.doCheckService(ctx, "KMS", ops)
+}
+
+// decryptKMS decrypts the ciphertext with the key and compares the result to the expected plaintext.
+func (c *checker) decryptKMS(ctx context.Context, ciphertextBlob, plaintext []byte) error {
+	var res *kms.DecryptOutput
+	err := c.timeKMS("Decrypt", func() (err error) {
+		res, err = c.kmsClient.Decrypt(ctx, &kms.DecryptInput{
+			// Specifying the key makes KMS fail if the ciphertext was encrypted with another key
+			KeyId:          &c.kmsKeyID,
+			CiphertextBlob: ciphertextBlob,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Never log the plaintext as it may be a data key
+	if !bytes.Equal(res.Plaintext, plaintext) {
+		return withStatus(statusCorruptData, fmt.Errorf("decrypted plaintext does not match the encrypted one"))
+	}
+	return nil
+}
+
+// timeKMS calls fn and records its duration as a request of method with the key, unless it fails.
+func (c *checker) timeKMS(method string, fn func() error) error {
+	start := time.Now()
+	if err := fn(); err != nil {
+		return err
+	}
+
+	c.kmsRequestDuration.WithLabelValues(c.kmsKeyID, method).Observe(time.Since(start).Seconds())
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statusFunctionError is the status of an invocation that the function returned an error for.
+const statusFunctionError = "FunctionError"
+
+// configureLambda configures the Lambda check from the environment variables.
+func (c *checker) configureLambda(cfg aws.Config) error {
+	c.lambdaInvokeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aws_lambda_invoke_duration_seconds",
+			Help:    "Time spent in RequestResponse invocations of the Lambda function, by whether the function reported a cold start.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"function", "start"},
+	)
+
+	c.lambdaFunctionName = os.Getenv("LAMBDA_FUNCTION_NAME")
+	if c.lambdaFunctionName == "" {
+		return nil
+	}
+
+	c.lambdaClient = lambda.NewFromConfig(cfg, c.lambdaOpts...)
+
+	c.lambdaInvocationType = lambdatypes.InvocationType(os.Getenv("LAMBDA_INVOCATION_TYPE"))
+	switch c.lambdaInvocationType {
+	case "":
+		c.lambdaInvocationType = lambdatypes.InvocationTypeRequestResponse
+	case lambdatypes.InvocationTypeRequestResponse, lambdatypes.InvocationTypeDryRun:
+	default:
+		return fmt.Errorf("LAMBDA_INVOCATION_TYPE must be RequestResponse or DryRun, got %q", c.lambdaInvocationType)
+	}
+
+	c.lambdaPayload = os.Getenv("LAMBDA_PAYLOAD")
+	c.lambdaExpectedResponse = os.Getenv("LAMBDA_EXPECTED_RESPONSE")
+
+	c.lambdaColdStartField = os.Getenv("LAMBDA_COLD_START_FIELD")
+	if c.lambdaColdStartField == "" {
+		c.lambdaColdStartField = "cold_start"
+	}
+
+	return nil
+}
+
+// doCheckLambda invokes the function with a probe payload, or with the DryRun invocation type.
+func (c *checker) doCheckLambda(ctx context.Context) {
+	c.doCheckService(ctx, "Lambda", []operation{
+		{
+			method: "Invoke",
+			operations: []func() error{
+				func() error {
+					payload := []byte(c.lambdaPayload)
+					if len(payload) == 0 {
+						payload, _ = json.Marshal(map[string]string{"aws_checker_probe_id": c.probeID()})
+					}
+
+					in := &lambda.InvokeInput{
+						FunctionName:   &c.lambdaFunctionName,
+						InvocationType: c.lambdaInvocationType,
+					}
+					if c.lambdaInvocationType != lambdatypes.InvocationTypeDryRun {
+						in.Payload = payload
+					}
+
+					start := time.Now()
+					res, err := c.lambdaClient.Invoke(ctx, in)
+					duration := time.Since(start)
+					if err != nil {
+						return err
+					}
+
+					if res.FunctionError != nil {
+						return withStatus(statusFunctionError, fmt.Errorf("function returned %s, %s", aws.ToString(res.FunctionError), lambdaPayloadSummary(res.Payload)))
+					}
+
+					if c.lambdaInvocationType == lambdatypes.InvocationTypeDryRun {
+						return nil
+					}
+
+					c.lambdaInvokeDuration.WithLabelValues(c.lambdaFunctionName, lambdaStart(res.Payload, c.lambdaColdStartField)).Observe(duration.Seconds())
+
+					if c.lambdaExpectedResponse != "" && !lambdaResponseEqual(res.Payload, []byte(c.lambdaExpectedResponse)) {
+						return withStatus(statusCorruptData, fmt.Errorf("function responded with an unexpected %s", lambdaPayloadSummary(res.Payload)))
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+// lambdaStart returns "cold" or "warm" depending on the boolean field of the JSON object in the response payload,
+// or "unknown" if the payload has no such field.
+func lambdaStart(payload []byte, field string) string {
+	var res map[string]any
+	if err := json.Unmarshal(payload, &res); err != nil {
+		return "unknown"
+	}
+
+	switch res[field] {
+	case true:
+		return "cold"
+	case false:
+		return "warm"
+	default:
+		return "unknown"
+	}
+}
+
+// lambdaResponseEqual returns true if the response payload is equal to the expected one.
+// JSON payloads are compared by their values, ignoring the formatting and the order of the object keys.
+func lambdaResponseEqual(payload, expected []byte) bool {
+	var p, e any
+	if json.Unmarshal(payload, &p) == nil && json.Unmarshal(expected, &e) == nil {
+		pj, _ := json.Marshal(p)
+		ej, _ := json.Marshal(e)
+		return bytes.Equal(pj, ej)
+	}
+
+	return bytes.Equal(bytes.TrimSpace(payload), bytes.TrimSpace(expected))
+}
+
+// lambdaPayloadSummary describes the payload by its length and hash,
+// as the response and the error of a function can carry arbitrary data that must not be logged.
+func lambdaPayloadSummary(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return fmt.Sprintf("payload of %d bytes with sha256 %s", len(payload), hex.EncodeToString(sum[:]))
+}
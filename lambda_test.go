@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLambdaStart(t *testing.T) {
+	require.Equal(t, "cold", lambdaStart([]byte(`{"cold_start": true, "ok": true}`), "cold_start"))
+	require.Equal(t, "warm", lambdaStart([]byte(`{"cold_start": false}`), "cold_start"))
+	require.Equal(t, "unknown", lambdaStart([]byte(`{"cold_start": "yes"}`), "cold_start"))
+	require.Equal(t, "unknown", lambdaStart([]byte(`{"ok": true}`), "cold_start"))
+	require.Equal(t, "unknown", lambdaStart([]byte(`"ok"`), "cold_start"))
+}
+
+func TestLambdaResponseEqual(t *testing.T) {
+	require.True(t, lambdaResponseEqual([]byte(`{"ok":true,"status":200}`), []byte(`{ "status": 200, "ok": true }`)))
+	require.False(t, lambdaResponseEqual([]byte(`{"ok":false}`), []byte(`{"ok":true}`)))
+	require.True(t, lambdaResponseEqual([]byte("pong\n"), []byte("pong")))
+	require.False(t, lambdaResponseEqual([]byte("pong"), []byte("ping")))
+}
+
+func TestDoCheckLambda(t *testing.T) {
+	var (
+		functionError string
+		response      string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler runs on another goroutine, so a wrong request fails the check instead of the test
+		if r.URL.Path != "/2015-03-31/functions/myfunction/invocations" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("X-Amz-Invocation-Type") == "DryRun" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if functionError != "" {
+			w.Header().Set("X-Amz-Function-Error", functionError)
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	defer srv.Close()
+
+	run := func(t *testing.T, env map[string]string) (*checker, operationStatus) {
+		t.Setenv("LAMBDA_FUNCTION_NAME", "myfunction")
+		for k, v := range env {
+			t.Setenv(k, v)
+		}
+
+		c := newTestChecker()
+		require.NoError(t, c.configureLambda(testAWSConfig(srv.URL)))
+
+		c.doCheckLambda(context.Background())
+
+		ops := c.status.operations()
+		require.Len(t, ops, 1)
+		require.Equal(t, "Lambda", ops[0].Service)
+		require.Equal(t, "Invoke", ops[0].Method)
+		return c, ops[0]
+	}
+
+	t.Run("success", func(t *testing.T) {
+		functionError, response = "", `{"ok": true, "cold_start": true}`
+
+		c, op := run(t, map[string]string{"LAMBDA_EXPECTED_RESPONSE": `{"ok": true, "cold_start": true}`})
+		require.Equal(t, "Success", op.Status)
+		require.Equal(t, 1, testutil.CollectAndCount(c.lambdaInvokeDuration))
+	})
+
+	t.Run("function error", func(t *testing.T) {
+		functionError, response = "Unhandled", `{"errorMessage": "password=hunter2"}`
+
+		c, op := run(t, nil)
+		require.Equal(t, statusFunctionError, op.Status)
+		require.Contains(t, op.LastError, "Unhandled")
+		require.NotContains(t, op.LastError, "hunter2")
+		require.Equal(t, 0, testutil.CollectAndCount(c.lambdaInvokeDuration))
+	})
+
+	t.Run("unexpected response", func(t *testing.T) {
+		functionError, response = "", `{"ok": false, "token": "hunter2"}`
+
+		_, op := run(t, map[string]string{"LAMBDA_EXPECTED_RESPONSE": `{"ok": true}`})
+		require.Equal(t, statusCorruptData, op.Status)
+		require.NotContains(t, op.LastError, "hunter2")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		functionError, response = "", ""
+
+		c, op := run(t, map[string]string{"LAMBDA_INVOCATION_TYPE": "DryRun"})
+		require.Equal(t, "Success", op.Status)
+		require.Equal(t, 0, testutil.CollectAndCount(c.lambdaInvokeDuration))
+	})
+}
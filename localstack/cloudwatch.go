@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func CloudWatchEndpointResolver() *cloudWatchEndpointResolver {
+	return &cloudWatchEndpointResolver{
+		baseResolver: cloudwatch.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type cloudWatchEndpointResolver struct {
+	baseResolver cloudwatch.EndpointResolverV2
+}
+
+func (r *cloudWatchEndpointResolver) ResolveEndpoint(ctx context.Context, params cloudwatch.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
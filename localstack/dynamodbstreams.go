@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func DynamoDBStreamsEndpointResolver() *dynamoDBStreamsEndpointResolver {
+	return &dynamoDBStreamsEndpointResolver{
+		baseResolver: dynamodbstreams.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type dynamoDBStreamsEndpointResolver struct {
+	baseResolver dynamodbstreams.EndpointResolverV2
+}
+
+func (r *dynamoDBStreamsEndpointResolver) ResolveEndpoint(ctx context.Context, params dynamodbstreams.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
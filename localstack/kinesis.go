@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func KinesisEndpointResolver() *kinesisEndpointResolver {
+	return &kinesisEndpointResolver{
+		baseResolver: kinesis.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type kinesisEndpointResolver struct {
+	baseResolver kinesis.EndpointResolverV2
+}
+
+func (r *kinesisEndpointResolver) ResolveEndpoint(ctx context.Context, params kinesis.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func KMSEndpointResolver() *kmsEndpointResolver {
+	return &kmsEndpointResolver{
+		baseResolver: kms.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type kmsEndpointResolver struct {
+	baseResolver kms.EndpointResolverV2
+}
+
+func (r *kmsEndpointResolver) ResolveEndpoint(ctx context.Context, params kms.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
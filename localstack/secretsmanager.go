@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func SecretsManagerEndpointResolver() *secretsManagerEndpointResolver {
+	return &secretsManagerEndpointResolver{
+		baseResolver: secretsmanager.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type secretsManagerEndpointResolver struct {
+	baseResolver secretsmanager.EndpointResolverV2
+}
+
+func (r *secretsManagerEndpointResolver) ResolveEndpoint(ctx context.Context, params secretsmanager.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
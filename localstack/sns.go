@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func SNSEndpointResolver() *snsEndpointResolver {
+	return &snsEndpointResolver{
+		baseResolver: sns.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type snsEndpointResolver struct {
+	baseResolver sns.EndpointResolverV2
+}
+
+func (r *snsEndpointResolver) ResolveEndpoint(ctx context.Context, params sns.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
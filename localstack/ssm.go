@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func SSMEndpointResolver() *ssmEndpointResolver {
+	return &ssmEndpointResolver{
+		baseResolver: ssm.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type ssmEndpointResolver struct {
+	baseResolver ssm.EndpointResolverV2
+}
+
+func (r *ssmEndpointResolver) ResolveEndpoint(ctx context.Context, params ssm.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
@@ -0,0 +1,25 @@
+package localstack
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+)
+
+func STSEndpointResolver() *stsEndpointResolver {
+	return &stsEndpointResolver{
+		baseResolver: sts.NewDefaultEndpointResolverV2(),
+	}
+}
+
+type stsEndpointResolver struct {
+	baseResolver sts.EndpointResolverV2
+}
+
+func (r *stsEndpointResolver) ResolveEndpoint(ctx context.Context, params sts.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	params.Endpoint = aws.String("http://localhost:4566")
+
+	return r.baseResolver.ResolveEndpoint(ctx, params)
+}
@@ -2,20 +2,37 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -99,19 +116,80 @@ func Run(ctx context.Context, opts ...Option) error {
 		return fmt.Errorf("unable to load SDK config, %v", err)
 	}
 
-	chkr := newChecker(cfg, requestDuration, opts...)
-	checkCtx, checkCancel := context.WithCancel(ctx)
+	chkr, err := newChecker(cfg, requestDuration, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to configure checker, %v", err)
+	}
+
+	for _, col := range chkr.collectors() {
+		registry.MustRegister(col)
+		defer registry.Unregister(col)
+	}
+
+	httpMux.Handle("/status", chkr.statusHandler())
+	httpMux.Handle("/readyz", chkr.health.readinessHandler())
+
+	var (
+		checkCtx, checkCancel = context.WithCancel(ctx)
+		checks                sync.WaitGroup
+	)
+
+	startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckS3)
+	startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckDynamoDB)
+	startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckSQS)
+
+	if chkr.s3MultipartParts > 0 {
+		startChecks(checkCtx, &checks, chkr.s3MultipartInterval, chkr.doCheckS3Multipart)
+	}
 
-	startChecks(checkCtx, chkr.awsAPICallInterval, chkr.doCheckS3)
-	startChecks(checkCtx, chkr.awsAPICallInterval, chkr.doCheckDynamoDB)
-	startChecks(checkCtx, chkr.awsAPICallInterval, chkr.doCheckSQS)
+	if chkr.s3ReplicationBucket != "" {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckS3Replication)
+	}
+
+	if len(chkr.dynamodbReplicaClients) > 0 {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckDynamoDBReplication)
+	}
+
+	if chkr.snsClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckSNS)
+	}
+
+	if chkr.kinesisClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckKinesis)
+	}
+
+	if chkr.lambdaClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckLambda)
+	}
+	if chkr.kmsClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckKMS)
+	}
+	if chkr.secretsManagerClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckSecretsManager)
+	}
+	if chkr.ssmClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckSSM)
+	}
+	if chkr.ecrClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckECR)
+	}
+	if chkr.stsClient != nil {
+		startChecks(checkCtx, &checks, chkr.awsAPICallInterval, chkr.doCheckSTS)
+	}
 
 	<-ctx.Done()
 
 	fmt.Println("Context is canceled, exiting...")
 
-	// Stop the checker
+	// Stop the checker, and wait for the running checks to return
+	// so that the cleanup does not race with them
 	checkCancel()
+	checks.Wait()
+
+	cleanupCtx, cleanupCancel := cleanupContext()
+	defer cleanupCancel()
+
+	chkr.cleanup(cleanupCtx)
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), httpServerGracefulShutdownTimeout)
@@ -130,11 +208,15 @@ func Run(ctx context.Context, opts ...Option) error {
 
 // startChecks runs the given function startChecks, each time the interval has passed,
 // until the context is canceled.
+// wg is done once the last run has returned.
 //
 // We intentionally use time.After instead of time.Ticker to have delay each check by the interval,
 // regardless of how long the previous check took.
-func startChecks(ctx context.Context, interval time.Duration, run func(ctx context.Context)) {
+func startChecks(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, run func(ctx context.Context)) {
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
+
 		for {
 			select {
 			case <-ctx.Done():
@@ -148,6 +230,18 @@ func startChecks(ctx context.Context, interval time.Duration, run func(ctx conte
 
 type checker struct {
 	requestDuration *prometheus.HistogramVec
+	// requestErrors counts the failed operations by the error codes.
+	requestErrors *prometheus.CounterVec
+
+	// expectedOutcomes are the expected outcomes of the operations other than success.
+	expectedOutcomes []expectedOutcome
+
+	// observers are notified of every result recorded by observe.
+	observers []observer
+
+	status *statusTracker
+	health *health
+	slo    *slo
 
 	s3Client     *s3.Client
 	dynamoClient *dynamodb.Client
@@ -158,16 +252,197 @@ type checker struct {
 	dynamodbTable string
 	sqsQueueURL   string
 
-	s3Opts       []func(*s3.Options)
-	sqsOpts      []func(*sqs.Options)
-	dynamodbOpts []func(*dynamodb.Options)
+	// s3ProbePrefix is the key prefix of the objects written by the S3 write-path checks.
+	// The write-path checks are disabled when it is empty.
+	s3ProbePrefix     string
+	s3ProbeObjectSize int
+	// s3ProbeObjects are the keys of the probe objects that have been written but not deleted yet,
+	// and the time they were written at.
+	s3ProbeObjectsMu sync.Mutex
+	s3ProbeObjects   map[string]time.Time
+
+	// s3VerifyContent enables reading the whole S3_KEY object and comparing it to
+	// s3ExpectedSHA256 and s3ExpectedETag, if any.
+	s3VerifyContent  bool
+	s3ExpectedSHA256 string
+	s3ExpectedETag   string
+
+	s3DownloadThroughput *prometheus.GaugeVec
+
+	// dynamodbPartitionKey and dynamodbSortKey are the key attributes of the probe item.
+	// dynamodbSortKey is nil when the table has no sort key.
+	dynamodbPartitionKey *dynamodbKey
+	dynamodbSortKey      *dynamodbKey
+	// dynamodbItemAttributes are the attributes written to the probe item other than the key and data attributes.
+	dynamodbItemAttributes map[string]dynamodbtypes.AttributeValue
+	// dynamodbReadLimit is the maximum number of items read by Scan and Query.
+	dynamodbReadLimit int32
+	// dynamodbTransactionCheck and dynamodbBatchCheck enable the optional transactional and batch operations.
+	dynamodbTransactionCheck bool
+	dynamodbBatchCheck       bool
+	// dynamodbReplicaClients are the clients of the replica regions of the global table, keyed by the region.
+	// The global table replication check is disabled when it is empty.
+	dynamodbReplicaClients          map[string]*dynamodb.Client
+	dynamodbReplicationTimeout      time.Duration
+	dynamodbReplicationPollInterval time.Duration
+	dynamodbReplicationLag          *prometheus.HistogramVec
+	// dynamodbPartiQLCheck and dynamodbConditionalWriteCheck enable the optional PartiQL and conditional write operations.
+	dynamodbPartiQLCheck          bool
+	dynamodbConditionalWriteCheck bool
+	// dynamodbStreamsClient reads the stream of the table for the streams check.
+	// The streams check is disabled when it is nil.
+	dynamodbStreamsClient       *dynamodbstreams.Client
+	dynamodbStreamsTimeout      time.Duration
+	dynamodbStreamsPollInterval time.Duration
+	// dynamodbProbeKeyPrefix is the prefix of the unique keys of the probe items.
+	dynamodbProbeKeyPrefix string
+	// dynamodbProbeItems are the IDs of the probe items that have been written but not deleted yet,
+	// and the time they were written at.
+	dynamodbProbeItemsMu sync.Mutex
+	dynamodbProbeItems   map[string]time.Time
+
+	// s3MultipartParts is the number of parts uploaded by the S3 multipart upload check.
+	// The multipart upload check is disabled when it is zero.
+	s3MultipartParts    int
+	s3MultipartPartSize int
+	s3MultipartInterval time.Duration
+
+	// s3PresignedCheck enables the check of presigned URLs for the probe object.
+	s3PresignedCheck bool
+
+	// s3ReplicationBucket is the destination bucket of the replication from s3Bucket.
+	// The S3 replication check is disabled when it is empty.
+	s3ReplicationBucket       string
+	s3ReplicationClient       *s3.Client
+	s3ReplicationTimeout      time.Duration
+	s3ReplicationPollInterval time.Duration
+	s3ReplicationLag          *prometheus.HistogramVec
+
+	// sqsRoundTripCheck enables sending probe messages to the queue and receiving them back,
+	// instead of only receiving messages.
+	sqsRoundTripCheck    bool
+	sqsReceiveTimeout    time.Duration
+	sqsVisibilityTimeout int32
+
+	// sqsFIFO is true when the queue is a FIFO queue, whose round-trip check sends a burst of sqsFIFOBurstSize messages.
+	sqsFIFO          bool
+	sqsFIFOBurstSize int
+
+	// sqsQueueDepthCheck enables exporting the approximate number of messages in the queue and its dead-letter queue.
+	sqsQueueDepthCheck         bool
+	sqsQueueMessages           *prometheus.GaugeVec
+	sqsDeadLetterQueueMessages *prometheus.GaugeVec
+	// cloudwatchClient reads the age of the oldest message in the queue, which the SQS API does not return,
+	// for the queue depth check.
+	cloudwatchClient    *cloudwatch.Client
+	sqsOldestMessageAge *prometheus.GaugeVec
+	// sqsOldestMessageAgeCheckedAt is when the age of the oldest message was last read,
+	// so that it is read at most once per sqsOldestMessageAgePeriod.
+	sqsOldestMessageAgeCheckedAt time.Time
+
+	// snsClient publishes to snsTopicARN for the SNS check.
+	// The SNS check is disabled when it is nil.
+	snsClient   *sns.Client
+	snsTopicARN string
+	// snsDeliveryQueueURL is the URL of the SQS queue subscribed to snsTopicARN, to confirm the delivery.
+	snsDeliveryQueueURL string
+
+	// kinesisClient puts and gets the records of kinesisStreamName for the Kinesis check.
+	// The Kinesis check is disabled when it is nil.
+	kinesisClient       *kinesis.Client
+	kinesisStreamName   string
+	kinesisReadTimeout  time.Duration
+	kinesisPollInterval time.Duration
+
+	// lambdaClient invokes lambdaFunctionName for the Lambda check.
+	// The Lambda check is disabled when it is nil.
+	lambdaClient         *lambda.Client
+	lambdaFunctionName   string
+	lambdaInvocationType lambdatypes.InvocationType
+	// lambdaPayload is the payload of the invocations. A JSON object with the probe ID is sent when it is empty.
+	lambdaPayload string
+	// lambdaExpectedResponse is compared to the response payload unless it is empty.
+	lambdaExpectedResponse string
+	// lambdaColdStartField is the boolean field in the response payload that the function sets to true on a cold start.
+	lambdaColdStartField string
+	lambdaInvokeDuration *prometheus.HistogramVec
+
+	// kmsClient encrypts and decrypts probe payloads with kmsKeyID for the KMS check.
+	// The KMS check is disabled when it is nil.
+	kmsClient               *kms.Client
+	kmsKeyID                string
+	kmsGenerateDataKeyCheck bool
+	kmsRequestDuration      *prometheus.HistogramVec
+
+	// secretsManagerClient reads secretsManagerSecretID for the Secrets Manager check.
+	// The Secrets Manager check is disabled when it is nil.
+	secretsManagerClient   *secretsmanager.Client
+	secretsManagerSecretID string
+	// secretsManagerExpectedVersionID and secretsManagerExpectedSHA256 are compared to the secret unless they are empty.
+	// The secret value itself is never compared, logged, or exported.
+	secretsManagerExpectedVersionID string
+	secretsManagerExpectedSHA256    string
+
+	// ssmClient reads ssmParameterName and the parameters under ssmParametersPath for the SSM Parameter Store check.
+	// The SSM Parameter Store check is disabled when it is nil.
+	ssmClient         *ssm.Client
+	ssmParameterName  string
+	ssmParametersPath string
+	ssmWithDecryption bool
+	ssmExpectedSHA256 string
+
+	// ecrClient gets ecrImageTag of ecrRepositoryName for the ECR check.
+	// The ECR check is disabled when it is nil.
+	ecrClient         *ecr.Client
+	ecrRepositoryName string
+	ecrImageTag       string
+	// ecrRegistryID is the account ID of the registry, or nil for the registry of the credentials.
+	ecrRegistryID *string
+	// ecrManifestCheck enables the HEAD request for the manifest to the registry.
+	ecrManifestCheck bool
+	// ecrRegistryEndpoint overrides the registry endpoint returned with the authorization token, like a VPC endpoint.
+	ecrRegistryEndpoint string
+
+	// stsClient checks the identity of credentials for the STS check.
+	// The STS check is disabled when it is nil.
+	stsClient   *sts.Client
+	credentials aws.CredentialsProvider
+	// callerIdentity exports the account and ARN returned by GetCallerIdentity.
+	callerIdentity *prometheus.GaugeVec
+	// credentialsExpiry has no labels, and exports nothing unless the credentials expire.
+	credentialsExpiry *prometheus.GaugeVec
+
+	// deliveryLatency is the end-to-end latency of the messages and records sent by the checks,
+	// from sending them to receiving them.
+	deliveryLatency *prometheus.HistogramVec
+
+	// instanceID identifies this checker instance in the names of the resources written by the checks.
+	instanceID string
+	probeSeq   atomic.Uint64
+
+	s3Opts              []func(*s3.Options)
+	sqsOpts             []func(*sqs.Options)
+	dynamodbOpts        []func(*dynamodb.Options)
+	dynamodbStreamsOpts []func(*dynamodbstreams.Options)
+	snsOpts             []func(*sns.Options)
+	kinesisOpts         []func(*kinesis.Options)
+	lambdaOpts          []func(*lambda.Options)
+	kmsOpts             []func(*kms.Options)
+	ecrOpts             []func(*ecr.Options)
+	secretsManagerOpts  []func(*secretsmanager.Options)
+	ssmOpts             []func(*ssm.Options)
+	stsOpts             []func(*sts.Options)
+	cloudwatchOpts      []func(*cloudwatch.Options)
+
+	// httpClient is used for the requests sent without the AWS SDK, like the ones to presigned URLs.
+	httpClient *http.Client
 
 	awsAPICallInterval time.Duration
 }
 
 type Option func(*checker)
 
-func newChecker(cfg aws.Config, requestDuration *prometheus.HistogramVec, opts ...Option) *checker {
+func newChecker(cfg aws.Config, requestDuration *prometheus.HistogramVec, opts ...Option) (*checker, error) {
 	c := &checker{}
 	for _, opt := range opts {
 		opt(c)
@@ -184,205 +459,322 @@ func newChecker(cfg aws.Config, requestDuration *prometheus.HistogramVec, opts .
 	c.dynamodbTable = os.Getenv("DYNAMODB_TABLE")
 	c.sqsQueueURL = os.Getenv("SQS_QUEUE_URL")
 
+	c.requestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aws_request_errors_total",
+			Help: "Number of failed operations by the error code.",
+		},
+		[]string{"service", "method", "code"},
+	)
+
+	c.deliveryLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aws_delivery_latency_seconds",
+			Help:    "Time from sending a probe message or record to receiving it back.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"service", "method"},
+	)
+
+	if err := c.configureS3(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureSQS(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureDynamoDB(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureSNS(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureKinesis(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureLambda(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureKMS(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureSecretsManager(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureSSM(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureECR(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := c.configureSTS(cfg); err != nil {
+		return nil, err
+	}
+
+	if c.instanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get hostname, %v", err)
+		}
+		c.instanceID = hostname
+	}
+
 	if c.awsAPICallInterval == 0 {
 		c.awsAPICallInterval = 1 * time.Second
 	}
 
-	return c
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	var err error
+
+	c.expectedOutcomes, err = newExpectedOutcomesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	c.status = newStatusTracker()
+	c.observers = append(c.observers, c.status)
+
+	c.health, err = newHealthFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	c.observers = append(c.observers, c.health)
+
+	n, err := newNotifierFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if n != nil {
+		c.health.listeners = append(c.health.listeners, n)
+	}
+
+	c.slo, err = newSLOFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if c.slo != nil {
+		c.observers = append(c.observers, c.slo)
+	}
+
+	return c, nil
 }
 
-func (c *checker) doCheckS3(ctx context.Context) {
-	// S3 GetObject
-	getStart := time.Now()
-	_, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: &c.s3Bucket,
-		Key:    &c.s3Key,
-	})
-	getDuration := time.Since(getStart).Seconds()
-	if ctx.Err() == context.Canceled {
-		log.Printf("context is canceled")
-		return
-	} else if err != nil {
-		log.Printf("failed to get object, %v", err)
-		c.requestDuration.WithLabelValues("S3", "GetObject", "Failure").Observe(getDuration)
-	} else {
-		c.requestDuration.WithLabelValues("S3", "GetObject", "Success").Observe(getDuration)
+// collectors returns the Prometheus collectors of the checker, other than the request duration.
+func (c *checker) collectors() []prometheus.Collector {
+	cs := []prometheus.Collector{c.requestErrors, c.health, c.s3DownloadThroughput, c.s3ReplicationLag, c.deliveryLatency, c.sqsQueueMessages, c.sqsDeadLetterQueueMessages, c.sqsOldestMessageAge, c.dynamodbReplicationLag, c.lambdaInvokeDuration, c.kmsRequestDuration}
+
+	if c.stsClient != nil {
+		cs = append(cs, c.callerIdentity, c.credentialsExpiry)
 	}
+
+	if c.slo != nil {
+		cs = append(cs, c.slo)
+	}
+
+	return cs
+}
+
+// cleanupTimeout is the time allowed to delete the resources written by the checks.
+const cleanupTimeout = 10 * time.Second
+
+// cleanupContext returns a new context to delete the resources written by the checks with.
+// The context of a check may have been canceled on shutdown, so it is not derived from that.
+func cleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cleanupTimeout)
+}
+
+// cleanup deletes the resources written by the checks that are left behind when the checks are stopped.
+func (c *checker) cleanup(ctx context.Context) {
+	c.cleanupS3ProbeObjects(ctx)
+	c.cleanupDynamoDBProbeItems(ctx)
+}
+
+// probeID returns an identifier unique to this checker instance and call,
+// to name the resources written by the checks.
+func (c *checker) probeID() string {
+	return fmt.Sprintf("%s-%d-%d", c.instanceID, time.Now().UnixNano(), c.probeSeq.Add(1))
+}
+
+// probeNumber returns a decimal number unique to this checker instance and call, which is probeID in a number,
+// to name the resources written by the checks whose names must be numbers.
+// It is the time in nanoseconds times 1000 plus the sequence number modulo 1000,
+// which does not fit in int64 and thus is formatted without any arithmetic.
+// Unlike probeID, it does not have the instance ID, so the instances are told apart only by the time.
+func (c *checker) probeNumber() string {
+	return fmt.Sprintf("%d%03d", time.Now().UnixNano(), c.probeSeq.Add(1)%1000)
+}
+
+// parseProbeID parses an identifier returned by probeID into the ID of the checker instance and the time it was generated at.
+// It returns false if id is not in the form of a probe ID.
+// The instance ID may contain hyphens, so id is parsed from the end.
+func parseProbeID(id string) (string, time.Time, bool) {
+	rest, seq, ok := cutLast(id, "-")
+	if !ok {
+		return "", time.Time{}, false
+	}
+	if _, err := strconv.ParseUint(seq, 10, 64); err != nil {
+		return "", time.Time{}, false
+	}
+
+	instanceID, nanos, ok := cutLast(rest, "-")
+	if !ok || instanceID == "" {
+		return "", time.Time{}, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return instanceID, time.Unix(0, n), true
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+type operationKey struct {
+	service, method string
+}
+
+// result is the outcome of a single check of an operation.
+type result struct {
+	service  string
+	method   string
+	status   string
+	duration time.Duration
+	err      error
+	time     time.Time
+}
+
+// statusError is an error recorded with a status other than "Failure",
+// like "CorruptData" when a request succeeded but returned unexpected data.
+type statusError struct {
+	status string
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// withStatus returns err to be recorded with the status.
+func withStatus(status string, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+// errorCodes returns the codes classifying the error for the aws_request_errors_total metric.
+//
+// It is the error code of the API error, like ThrottlingException, or the status of the error recorded with one.
+// A canceled DynamoDB transaction is classified by its cancellation reasons, like TransactionCanceledException/ConditionalCheckFailed.
+func errorCodes(err error) []string {
+	var tce *dynamodbtypes.TransactionCanceledException
+	if errors.As(err, &tce) {
+		var (
+			codes []string
+			seen  = make(map[string]bool)
+		)
+		for _, r := range tce.CancellationReasons {
+			code := aws.ToString(r.Code)
+			if code == "" || code == "None" || seen[code] {
+				continue
+			}
+			seen[code] = true
+			codes = append(codes, tce.ErrorCode()+"/"+code)
+		}
+		if len(codes) > 0 {
+			return codes
+		}
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return []string{apiErr.ErrorCode()}
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return []string{se.status}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return []string{"DeadlineExceeded"}
+	}
+
+	return []string{"Unknown"}
+}
+
+// observer receives every result recorded by the checker.
+// Implementations must be safe for concurrent use, because each service is checked in its own goroutine.
+type observer interface {
+	observe(r result)
 }
 
-func (c *checker) doCheckSQS(ctx context.Context) {
-	// SQS ReceiveMessage
-	sqsStart := time.Now()
-	_, err := c.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-		QueueUrl: &c.sqsQueueURL,
-	})
-	sqsDuration := time.Since(sqsStart).Seconds()
-	if ctx.Err() == context.Canceled {
-		log.Printf("context is canceled")
-		return
+// observe records the result to the request duration metric and passes it to the observers.
+func (c *checker) observe(service, method string, duration time.Duration, err error) {
+	err = c.outcome(service, method, err)
+
+	status := "Success"
+
+	var se *statusError
+	if errors.As(err, &se) {
+		status = se.status
 	} else if err != nil {
-		log.Printf("failed to receive message, %v", err)
-		c.requestDuration.WithLabelValues("SQS", "ReceiveMessage", "Failure").Observe(sqsDuration)
-	} else {
-		c.requestDuration.WithLabelValues("SQS", "ReceiveMessage", "Success").Observe(sqsDuration)
+		status = "Failure"
+	}
+
+	r := result{
+		service:  service,
+		method:   method,
+		status:   status,
+		duration: duration,
+		err:      err,
+		time:     time.Now(),
+	}
+
+	c.requestDuration.WithLabelValues(r.service, r.method, r.status).Observe(r.duration.Seconds())
+
+	if err != nil {
+		for _, code := range errorCodes(err) {
+			c.requestErrors.WithLabelValues(r.service, r.method, code).Inc()
+		}
+	}
+
+	for _, o := range c.observers {
+		o.observe(r)
 	}
 }
 
+// operation is a sequence of steps recorded as a single method.
+// A step expected to fail, like a conditional write that must be rejected, is wrapped with expectError.
 type operation struct {
 	method     string
 	operations []func() error
 }
 
-func (c *checker) doCheckDynamoDB(ctx context.Context) {
-	c.doCheckService(ctx, "DynamoDB", []operation{
-		{
-			method: "Scan",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.Scan(ctx, &dynamodb.ScanInput{
-						TableName: &c.dynamodbTable,
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "PutItem",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
-						TableName: &c.dynamodbTable,
-						Item: map[string]dynamodbtypes.AttributeValue{
-							"id":   &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-							"data": &dynamodbtypes.AttributeValueMemberS{Value: "test-data"},
-						},
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "UpdateItem",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-						TableName: &c.dynamodbTable,
-						Key: map[string]dynamodbtypes.AttributeValue{
-							"id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-						UpdateExpression: aws.String("SET #data = :data"),
-						ExpressionAttributeNames: map[string]string{
-							"#data": "data",
-						},
-						ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
-							":data": &dynamodbtypes.AttributeValueMemberS{Value: "updated-data"},
-						},
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "DeleteItem",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-						TableName: &c.dynamodbTable,
-						Key: map[string]dynamodbtypes.AttributeValue{
-							"id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "GetItem",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
-						TableName: &c.dynamodbTable,
-						Key: map[string]dynamodbtypes.AttributeValue{
-							"id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "GetItemConsistent",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
-						TableName: &c.dynamodbTable,
-						Key: map[string]dynamodbtypes.AttributeValue{
-							"id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-						ConsistentRead: aws.Bool(true),
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "Query",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.Query(ctx, &dynamodb.QueryInput{
-						TableName:              &c.dynamodbTable,
-						KeyConditionExpression: aws.String("id = :id"),
-						ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
-							":id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "QueryConsistent",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.Query(ctx, &dynamodb.QueryInput{
-						TableName:              &c.dynamodbTable,
-						KeyConditionExpression: aws.String("id = :id"),
-						ExpressionAttributeValues: map[string]dynamodbtypes.AttributeValue{
-							":id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-						ConsistentRead: aws.Bool(true),
-					})
-					return err
-				},
-			},
-		},
-		{
-			method: "PutGetItemConsistent",
-			operations: []func() error{
-				func() error {
-					_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
-						TableName: &c.dynamodbTable,
-						Item: map[string]dynamodbtypes.AttributeValue{
-							"id":   &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-							"data": &dynamodbtypes.AttributeValueMemberS{Value: "test-data"},
-						},
-					})
-					return err
-				},
-				func() error {
-					_, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
-						TableName: &c.dynamodbTable,
-						Key: map[string]dynamodbtypes.AttributeValue{
-							"id": &dynamodbtypes.AttributeValueMemberS{Value: "test-id"},
-						},
-						ConsistentRead: aws.Bool(true),
-					})
-					return err
-				},
-			},
-		},
-	})
-}
+// errSkipOperation is returned by a step to skip the rest of the operation without recording it,
+// when there is nothing to check because of the failure of a previous operation.
+var errSkipOperation = errors.New("operation skipped")
 
 // doCheckService is a helper function to check a service.
 // It takes a list of operations to be checked and a service name.
@@ -407,16 +799,16 @@ func (c *checker) doCheckService(ctx context.Context, service string, operations
 			}
 		}
 
-		duration := time.Since(start).Seconds()
+		duration := time.Since(start)
 
 		if ctx.Err() == context.Canceled {
 			log.Printf("context is canceled")
 			return
-		} else if opErr != nil {
-			log.Printf("failed to %s, %v", op.method, opErr)
-			c.requestDuration.WithLabelValues(service, op.method, "Failure").Observe(duration)
-		} else {
-			c.requestDuration.WithLabelValues(service, op.method, "Success").Observe(duration)
+		} else if errors.Is(opErr, errSkipOperation) {
+			continue
+		} else if err := c.outcome(service, op.method, opErr); err != nil {
+			log.Printf("failed to %s, %v", op.method, err)
 		}
+		c.observe(service, op.method, duration, opErr)
 	}
 }
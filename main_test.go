@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"syscall"
 	"testing"
@@ -13,12 +15,23 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/cw-sakamoto/sample/localstack"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
@@ -28,6 +41,25 @@ type testcase struct {
 	setupS3  bool
 	setupDDB bool
 	setupSQS bool
+	// setupSNS creates an SNS topic with an SQS queue subscribed to it,
+	// and sets SNS_TOPIC_ARN and SNS_DELIVERY_QUEUE_URL to them.
+	setupSNS bool
+	// setupKinesis creates a Kinesis stream and sets KINESIS_STREAM_NAME to it.
+	setupKinesis bool
+	// setupKMS creates a KMS key and sets KMS_KEY_ID to it.
+	setupKMS bool
+	// setupSecrets creates a Secrets Manager secret and SSM parameters with the value "hello",
+	// and sets SECRETS_MANAGER_SECRET_ID, SSM_PARAMETER_NAME, and SSM_PARAMETERS_PATH to them.
+	setupSecrets bool
+
+	// env is the environment variables to set while running the checker,
+	// in addition to the ones set before running the tests.
+	env map[string]string
+
+	// services limits the compared labels to the ones of the services,
+	// so that the test case of a check lists only the labels the check adds.
+	// Every label is compared when it is empty.
+	services []string
 
 	okLabels []labels
 	ngLabels []labels
@@ -39,6 +71,7 @@ func TestRun(t *testing.T) {
 			setupS3:  true,
 			setupDDB: true,
 			setupSQS: true,
+			services: []string{"S3", "SQS", "DynamoDB"},
 			okLabels: []labels{
 				{"S3", "GetObject", "Success"},
 				{"SQS", "ReceiveMessage", "Success"},
@@ -73,6 +106,7 @@ func TestRun(t *testing.T) {
 			setupS3:  false,
 			setupDDB: true,
 			setupSQS: true,
+			services: []string{"S3", "SQS", "DynamoDB"},
 			okLabels: []labels{
 				{"S3", "GetObject", "Failure"},
 				{"SQS", "ReceiveMessage", "Success"},
@@ -107,6 +141,7 @@ func TestRun(t *testing.T) {
 			setupS3:  true,
 			setupDDB: true,
 			setupSQS: false,
+			services: []string{"S3", "SQS", "DynamoDB"},
 			okLabels: []labels{
 				{"S3", "GetObject", "Success"},
 				{"SQS", "ReceiveMessage", "Failure"},
@@ -141,6 +176,7 @@ func TestRun(t *testing.T) {
 			setupS3:  true,
 			setupDDB: false,
 			setupSQS: true,
+			services: []string{"S3", "SQS", "DynamoDB"},
 			okLabels: []labels{
 				{"S3", "GetObject", "Success"},
 				{"SQS", "ReceiveMessage", "Success"},
@@ -169,6 +205,487 @@ func TestRun(t *testing.T) {
 			},
 		})
 	})
+
+	t.Run("s3 write path", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"S3_PROBE_PREFIX":      "aws-checker/",
+				"S3_PROBE_OBJECT_SIZE": "4096",
+				"S3_VERIFY_CONTENT":    "true",
+				// The SHA-256 of "hello", which is the content put by setupS3BucketAndObject
+				"S3_EXPECTED_SHA256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "Success"},
+				{"S3", "PutObject", "Success"},
+				{"S3", "HeadObject", "Success"},
+				{"S3", "ListObjectsV2", "Success"},
+				{"S3", "GetProbeObject", "Success"},
+				{"S3", "DeleteObject", "Success"},
+			},
+			ngLabels: []labels{
+				{"S3", "PutObject", "Failure"},
+				{"S3", "HeadObject", "Failure"},
+				{"S3", "ListObjectsV2", "Failure"},
+				{"S3", "GetProbeObject", "Failure"},
+				{"S3", "GetProbeObject", "CorruptData"},
+				{"S3", "DeleteObject", "Failure"},
+			},
+		})
+	})
+
+	t.Run("s3 multipart upload", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"S3_PROBE_PREFIX":       "aws-checker/",
+				"S3_MULTIPART_PARTS":    "2",
+				"S3_MULTIPART_INTERVAL": "10ms",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "Success"},
+				{"S3", "PutObject", "Success"},
+				{"S3", "HeadObject", "Success"},
+				{"S3", "ListObjectsV2", "Success"},
+				{"S3", "GetProbeObject", "Success"},
+				{"S3", "DeleteObject", "Success"},
+				{"S3", "CreateMultipartUpload", "Success"},
+				{"S3", "UploadPart", "Success"},
+				{"S3", "CompleteMultipartUpload", "Success"},
+				{"S3", "DeleteMultipartObject", "Success"},
+			},
+			ngLabels: []labels{
+				{"S3", "CreateMultipartUpload", "Failure"},
+				{"S3", "UploadPart", "Failure"},
+				{"S3", "CompleteMultipartUpload", "Failure"},
+				{"S3", "DeleteMultipartObject", "Failure"},
+				{"S3", "AbortMultipartUpload", "Success"},
+				{"S3", "AbortMultipartUpload", "Failure"},
+			},
+		})
+	})
+
+	t.Run("s3 presigned urls", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"S3_PROBE_PREFIX":    "aws-checker/",
+				"S3_PRESIGNED_CHECK": "true",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "Success"},
+				{"S3", "PutObject", "Success"},
+				{"S3", "HeadObject", "Success"},
+				{"S3", "ListObjectsV2", "Success"},
+				{"S3", "GetProbeObject", "Success"},
+				{"S3", "DeleteObject", "Success"},
+				{"S3", "PresignedPutObject", "Success"},
+				{"S3", "PresignedGetObject", "Success"},
+				{"S3", "DeletePresignedObject", "Success"},
+			},
+			ngLabels: []labels{
+				{"S3", "PresignedPutObject", "Failure"},
+				{"S3", "PresignedGetObject", "Failure"},
+				{"S3", "PresignedGetObject", "CorruptData"},
+				{"S3", "DeletePresignedObject", "Failure"},
+			},
+		})
+	})
+
+	t.Run("s3 replication", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"S3_PROBE_PREFIX": "aws-checker/",
+				// localstack does not replicate objects, so we use the source bucket as the destination,
+				// which makes the object show up immediately.
+				"S3_REPLICATION_DESTINATION_BUCKET": os.Getenv("S3_BUCKET"),
+				"S3_REPLICATION_POLL_INTERVAL":      "10ms",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "Success"},
+				{"S3", "PutObject", "Success"},
+				{"S3", "HeadObject", "Success"},
+				{"S3", "ListObjectsV2", "Success"},
+				{"S3", "GetProbeObject", "Success"},
+				{"S3", "DeleteObject", "Success"},
+				{"S3", "PutReplicationObject", "Success"},
+				{"S3", "Replication", "Success"},
+			},
+			ngLabels: []labels{
+				{"S3", "PutReplicationObject", "Failure"},
+				{"S3", "Replication", "Failure"},
+				{"S3", "Replication", "Timeout"},
+			},
+		})
+	})
+
+	t.Run("sqs round trip", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"SQS_ROUND_TRIP_CHECK": "true",
+				"SQS_RECEIVE_TIMEOUT":  "2s",
+			},
+			services: []string{"SQS"},
+			okLabels: []labels{
+				{"SQS", "SendMessage", "Success"},
+				{"SQS", "ReceiveMessage", "Success"},
+				{"SQS", "DeleteMessage", "Success"},
+			},
+			ngLabels: []labels{
+				{"SQS", "SendMessage", "Failure"},
+				{"SQS", "ReceiveMessage", "Failure"},
+				{"SQS", "DeleteMessage", "Failure"},
+			},
+		})
+	})
+
+	t.Run("sqs fifo", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"SQS_QUEUE_URL":        os.Getenv("SQS_QUEUE_URL") + ".fifo",
+				"SQS_ROUND_TRIP_CHECK": "true",
+				"SQS_RECEIVE_TIMEOUT":  "2s",
+			},
+			services: []string{"SQS"},
+			okLabels: []labels{
+				{"SQS", "SendMessageBatch", "Success"},
+				{"SQS", "ReceiveMessage", "Success"},
+			},
+			ngLabels: []labels{
+				{"SQS", "SendMessageBatch", "Failure"},
+				{"SQS", "ReceiveMessage", "Failure"},
+				{"SQS", "ReceiveMessage", "OrderViolation"},
+				{"SQS", "ReceiveMessage", "Duplicate"},
+			},
+		})
+	})
+
+	t.Run("sqs queue depth", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"SQS_QUEUE_DEPTH_CHECK": "true",
+			},
+			services: []string{"SQS", "CloudWatch"},
+			okLabels: []labels{
+				{"SQS", "ReceiveMessage", "Success"},
+				{"SQS", "GetQueueAttributes", "Success"},
+				{"CloudWatch", "GetMetricData", "Success"},
+			},
+			ngLabels: []labels{
+				{"SQS", "ReceiveMessage", "Failure"},
+				{"SQS", "GetQueueAttributes", "Failure"},
+				{"CloudWatch", "GetMetricData", "Failure"},
+			},
+		})
+	})
+
+	t.Run("dynamodb composite key", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"DYNAMODB_PARTITION_KEY":   "pk",
+				"DYNAMODB_SORT_KEY":        "sk",
+				"DYNAMODB_SORT_KEY_TYPE":   "N",
+				"DYNAMODB_SORT_KEY_VALUE":  "1",
+				"DYNAMODB_ITEM_ATTRIBUTES": `{"ttl": 1700000000, "owner": "aws-checker"}`,
+				"DYNAMODB_READ_LIMIT":      "10",
+			},
+			services: []string{"DynamoDB"},
+			okLabels: dynamodbLabels(),
+			ngLabels: []labels{
+				{"DynamoDB", "PutItem", "Failure"},
+				{"DynamoDB", "Query", "Failure"},
+			},
+		})
+	})
+
+	t.Run("dynamodb transaction and batch", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"DYNAMODB_TRANSACTION_CHECK": "true",
+				"DYNAMODB_BATCH_CHECK":       "true",
+			},
+			services: []string{"DynamoDB"},
+			okLabels: dynamodbLabels(
+				labels{"DynamoDB", "TransactWriteItems", "Success"},
+				labels{"DynamoDB", "TransactGetItems", "Success"},
+				labels{"DynamoDB", "BatchWriteItem", "Success"},
+				labels{"DynamoDB", "BatchGetItem", "Success"},
+			),
+			ngLabels: []labels{
+				{"DynamoDB", "TransactWriteItems", "Failure"},
+				{"DynamoDB", "TransactGetItems", "Failure"},
+				{"DynamoDB", "TransactGetItems", "LostUpdate"},
+				{"DynamoDB", "BatchWriteItem", "Failure"},
+				{"DynamoDB", "BatchGetItem", "Failure"},
+				{"DynamoDB", "BatchGetItem", "LostUpdate"},
+			},
+		})
+	})
+
+	t.Run("dynamodb replication", func(t *testing.T) {
+		// localstack does not replicate global tables across regions,
+		// so we use the source region as the replica region.
+		region := os.Getenv("AWS_DEFAULT_REGION")
+
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"DYNAMODB_REPLICA_REGIONS":           region,
+				"DYNAMODB_REPLICATION_POLL_INTERVAL": "10ms",
+			},
+			services: []string{"DynamoDB"},
+			okLabels: dynamodbLabels(
+				labels{"DynamoDB", "PutReplicationItem", "Success"},
+				labels{"DynamoDB", "Replication/" + region, "Success"},
+			),
+			ngLabels: []labels{
+				{"DynamoDB", "PutReplicationItem", "Failure"},
+				{"DynamoDB", "Replication/" + region, "Failure"},
+				{"DynamoDB", "Replication/" + region, "Timeout"},
+			},
+		})
+	})
+
+	t.Run("dynamodb streams", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"DYNAMODB_STREAMS_CHECK":         "true",
+				"DYNAMODB_STREAMS_TIMEOUT":       "2s",
+				"DYNAMODB_STREAMS_POLL_INTERVAL": "10ms",
+			},
+			services: []string{"DynamoDB"},
+			okLabels: dynamodbLabels(
+				labels{"DynamoDB", "DescribeStream", "Success"},
+				labels{"DynamoDB", "GetShardIterator", "Success"},
+				labels{"DynamoDB", "PutStreamItem", "Success"},
+				labels{"DynamoDB", "GetRecords", "Success"},
+			),
+			ngLabels: []labels{
+				{"DynamoDB", "DescribeStream", "Failure"},
+				{"DynamoDB", "GetShardIterator", "Failure"},
+				{"DynamoDB", "PutStreamItem", "Failure"},
+				{"DynamoDB", "GetRecords", "Failure"},
+				{"DynamoDB", "GetRecords", "Timeout"},
+			},
+		})
+	})
+
+	t.Run("dynamodb partiql and conditional write", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"DYNAMODB_PARTIQL_CHECK":           "true",
+				"DYNAMODB_CONDITIONAL_WRITE_CHECK": "true",
+			},
+			services: []string{"DynamoDB"},
+			okLabels: dynamodbLabels(
+				labels{"DynamoDB", "ExecuteStatementUpdate", "Success"},
+				labels{"DynamoDB", "ExecuteStatementSelect", "Success"},
+				labels{"DynamoDB", "ConditionalPutItem", "Success"},
+			),
+			ngLabels: []labels{
+				{"DynamoDB", "ExecuteStatementUpdate", "Failure"},
+				{"DynamoDB", "ExecuteStatementSelect", "Failure"},
+				{"DynamoDB", "ExecuteStatementSelect", "LostUpdate"},
+				{"DynamoDB", "ConditionalPutItem", "Failure"},
+				{"DynamoDB", "ConditionalPutItem", "UnexpectedSuccess"},
+			},
+		})
+	})
+
+	t.Run("expected outcomes", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  false,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"EXPECTED_OUTCOMES": "S3/GetObject=NoSuchBucket,DynamoDB/Scan=Success",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "Success"},
+			},
+			ngLabels: []labels{
+				{"S3", "GetObject", "Failure"},
+				{"S3", "GetObject", "UnexpectedSuccess"},
+			},
+		})
+	})
+
+	t.Run("sns", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			setupSNS: true,
+			env: map[string]string{
+				"SQS_RECEIVE_TIMEOUT": "2s",
+			},
+			services: []string{"SNS"},
+			okLabels: []labels{
+				{"SNS", "Publish", "Success"},
+				{"SNS", "ReceiveMessage", "Success"},
+				{"SNS", "DeleteMessage", "Success"},
+			},
+			ngLabels: []labels{
+				{"SNS", "Publish", "Failure"},
+				{"SNS", "ReceiveMessage", "Failure"},
+				{"SNS", "DeleteMessage", "Failure"},
+			},
+		})
+	})
+
+	t.Run("kinesis", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:      true,
+			setupDDB:     true,
+			setupSQS:     true,
+			setupKinesis: true,
+			env: map[string]string{
+				"KINESIS_POLL_INTERVAL": "10ms",
+			},
+			services: []string{"Kinesis"},
+			okLabels: []labels{
+				{"Kinesis", "DescribeStreamSummary", "Success"},
+				{"Kinesis", "PutRecord", "Success"},
+				{"Kinesis", "GetShardIterator", "Success"},
+				{"Kinesis", "GetRecords", "Success"},
+			},
+			ngLabels: []labels{
+				{"Kinesis", "DescribeStreamSummary", "Failure"},
+				{"Kinesis", "PutRecord", "Failure"},
+				{"Kinesis", "GetShardIterator", "Failure"},
+				{"Kinesis", "GetRecords", "Failure"},
+				{"Kinesis", "GetRecords", "Timeout"},
+				{"Kinesis", "GetRecords", "CorruptData"},
+			},
+		})
+	})
+
+	t.Run("sts", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			services: []string{"STS"},
+			okLabels: []labels{
+				{"STS", "RetrieveCredentials", "Success"},
+				{"STS", "GetCallerIdentity", "Success"},
+			},
+			ngLabels: []labels{
+				{"STS", "RetrieveCredentials", "Failure"},
+				{"STS", "GetCallerIdentity", "Failure"},
+			},
+		})
+	})
+
+	t.Run("kms", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			setupKMS: true,
+			env: map[string]string{
+				"KMS_GENERATE_DATA_KEY_CHECK": "true",
+			},
+			services: []string{"KMS"},
+			okLabels: []labels{
+				{"KMS", "Encrypt", "Success"},
+				{"KMS", "Decrypt", "Success"},
+				{"KMS", "GenerateDataKey", "Success"},
+			},
+			ngLabels: []labels{
+				{"KMS", "Encrypt", "Failure"},
+				{"KMS", "Decrypt", "Failure"},
+				{"KMS", "Decrypt", "CorruptData"},
+				{"KMS", "GenerateDataKey", "Failure"},
+				{"KMS", "GenerateDataKey", "CorruptData"},
+			},
+		})
+	})
+
+	t.Run("secrets manager and ssm", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:      true,
+			setupDDB:     true,
+			setupSQS:     true,
+			setupSecrets: true,
+			env: map[string]string{
+				"SECRETS_MANAGER_EXPECTED_SHA256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				"SSM_PARAMETER_EXPECTED_SHA256":   "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				"SSM_WITH_DECRYPTION":             "true",
+			},
+			services: []string{"SecretsManager", "SSM"},
+			okLabels: []labels{
+				{"SecretsManager", "GetSecretValue", "Success"},
+				{"SSM", "GetParameter", "Success"},
+				{"SSM", "GetParametersByPath", "Success"},
+			},
+			ngLabels: []labels{
+				{"SecretsManager", "GetSecretValue", "Failure"},
+				{"SecretsManager", "GetSecretValue", "CorruptData"},
+				{"SSM", "GetParameter", "Failure"},
+				{"SSM", "GetParameter", "CorruptData"},
+				{"SSM", "GetParametersByPath", "Failure"},
+			},
+		})
+	})
+
+	t.Run("s3 corrupt data", func(t *testing.T) {
+		checkRun(t, testcase{
+			setupS3:  true,
+			setupDDB: true,
+			setupSQS: true,
+			env: map[string]string{
+				"S3_VERIFY_CONTENT":  "true",
+				"S3_EXPECTED_SHA256": "0000000000000000000000000000000000000000000000000000000000000000",
+			},
+			services: []string{"S3"},
+			okLabels: []labels{
+				{"S3", "GetObject", "CorruptData"},
+			},
+			ngLabels: []labels{
+				{"S3", "GetObject", "Success"},
+				{"S3", "GetObject", "Failure"},
+			},
+		})
+	})
 }
 
 func checkRun(t *testing.T, tc testcase) {
@@ -180,6 +697,10 @@ func checkRun(t *testing.T, tc testcase) {
 		sigs = make(chan os.Signal, 1)
 	)
 
+	for k, v := range tc.env {
+		t.Setenv(k, v)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -207,12 +728,41 @@ func checkRun(t *testing.T, tc testcase) {
 		}()
 	}
 
+	if tc.setupSNS {
+		topicARN, queueURL := setupSNSTopic(t, ctx, awsConfig, localstack.SNSEndpointResolver(), sqsEndpointResolver)
+		t.Setenv("SNS_TOPIC_ARN", topicARN)
+		t.Setenv("SNS_DELIVERY_QUEUE_URL", queueURL)
+	}
+
+	if tc.setupKinesis {
+		t.Setenv("KINESIS_STREAM_NAME", setupKinesisStream(t, ctx, awsConfig, localstack.KinesisEndpointResolver()))
+	}
+
+	if tc.setupSecrets {
+		t.Setenv("SECRETS_MANAGER_SECRET_ID", setupSecretsManagerSecret(t, ctx, awsConfig, localstack.SecretsManagerEndpointResolver()))
+		name, path := setupSSMParameters(t, ctx, awsConfig, localstack.SSMEndpointResolver())
+		t.Setenv("SSM_PARAMETER_NAME", name)
+		t.Setenv("SSM_PARAMETERS_PATH", path)
+	}
+
+	if tc.setupKMS {
+		t.Setenv("KMS_KEY_ID", setupKMSKey(t, ctx, awsConfig, localstack.KMSEndpointResolver()))
+	}
+
 	go func() {
 		runErr <- Run(ContextWithSignal(ctx, sigs), func(c *checker) {
 			// Use localstack for S3 and DynamoDB
 			c.s3Opts = append(c.s3Opts, s3.WithEndpointResolverV2(s3EndpointResolver))
 			c.sqsOpts = append(c.sqsOpts, sqs.WithEndpointResolverV2(sqsEndpointResolver))
 			c.dynamodbOpts = append(c.dynamodbOpts, dynamodb.WithEndpointResolverV2(dynamodbEndpointResolver))
+			c.kinesisOpts = append(c.kinesisOpts, kinesis.WithEndpointResolverV2(localstack.KinesisEndpointResolver()))
+			c.snsOpts = append(c.snsOpts, sns.WithEndpointResolverV2(localstack.SNSEndpointResolver()))
+			c.dynamodbStreamsOpts = append(c.dynamodbStreamsOpts, dynamodbstreams.WithEndpointResolverV2(localstack.DynamoDBStreamsEndpointResolver()))
+			c.kmsOpts = append(c.kmsOpts, kms.WithEndpointResolverV2(localstack.KMSEndpointResolver()))
+			c.secretsManagerOpts = append(c.secretsManagerOpts, secretsmanager.WithEndpointResolverV2(localstack.SecretsManagerEndpointResolver()))
+			c.ssmOpts = append(c.ssmOpts, ssm.WithEndpointResolverV2(localstack.SSMEndpointResolver()))
+			c.stsOpts = append(c.stsOpts, sts.WithEndpointResolverV2(localstack.STSEndpointResolver()))
+			c.cloudwatchOpts = append(c.cloudwatchOpts, cloudwatch.WithEndpointResolverV2(localstack.CloudWatchEndpointResolver()))
 			c.awsAPICallInterval = 1 * time.Millisecond
 		})
 
@@ -262,6 +812,7 @@ func checkRun(t *testing.T, tc testcase) {
 				lastMetrics = nil
 				continue
 			}
+			mm = filterLabels(mm, tc.services)
 
 			lastErr = nil
 			lastMetrics = mm
@@ -323,6 +874,39 @@ type labels struct {
 	service, method, status string
 }
 
+// dynamodbLabels returns the labels of the DynamoDB operations that are always checked,
+// followed by the extra ones.
+func dynamodbLabels(extra ...labels) []labels {
+	return append([]labels{
+		{"DynamoDB", "DeleteItem", "Success"},
+		{"DynamoDB", "GetItem", "Success"},
+		{"DynamoDB", "GetItemConsistent", "Success"},
+		{"DynamoDB", "PutGetItemConsistent", "Success"},
+		{"DynamoDB", "PutItem", "Success"},
+		{"DynamoDB", "Query", "Success"},
+		{"DynamoDB", "QueryConsistent", "Success"},
+		{"DynamoDB", "Scan", "Success"},
+		{"DynamoDB", "UpdateItem", "Success"},
+	}, extra...)
+}
+
+// filterLabels returns the labels of the services in mm.
+// It returns mm as is when services is empty.
+func filterLabels(mm map[labels]struct{}, services []string) map[labels]struct{} {
+	if len(services) == 0 {
+		return mm
+	}
+
+	filtered := make(map[labels]struct{})
+	for l := range mm {
+		if slices.Contains(services, l.service) {
+			filtered[l] = struct{}{}
+		}
+	}
+
+	return filtered
+}
+
 func parseMetrics(t *testing.T, m string) (map[labels]struct{}, error) {
 	p := expfmt.NewTextParser(model.UTF8Validation)
 	mf, err := p.TextToMetricFamilies(strings.NewReader(m))
@@ -398,6 +982,52 @@ func setupS3BucketAndObject(t *testing.T, ctx context.Context, awsConfig aws.Con
 	})
 }
 
+// newTestChecker returns a checker recording the results to its status tracker, for the unit tests of the checks.
+func newTestChecker() *checker {
+	c := &checker{
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: "aws_request_duration_seconds"},
+			[]string{"service", "method", "status"},
+		),
+		requestErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "aws_request_errors_total"},
+			[]string{"service", "method", "code"},
+		),
+		status:             newStatusTracker(),
+		instanceID:         "aws-checker",
+		httpClient:         &http.Client{Timeout: 5 * time.Second},
+		awsAPICallInterval: time.Millisecond,
+	}
+	c.observers = append(c.observers, c.status)
+	return c
+}
+
+// testAWSConfig returns the AWS config to send the requests to the test server at url.
+func testAWSConfig(url string) aws.Config {
+	return aws.Config{
+		Region:           "us-east-1",
+		Credentials:      aws.AnonymousCredentials{},
+		BaseEndpoint:     aws.String(url),
+		RetryMaxAttempts: 1,
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	require.Equal(t, []string{"TransactionCanceledException/ConditionalCheckFailed", "TransactionCanceledException/ThrottlingError"}, errorCodes(&dynamodbtypes.TransactionCanceledException{
+		CancellationReasons: []dynamodbtypes.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("ThrottlingError")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	}))
+	require.Equal(t, []string{"TransactionCanceledException"}, errorCodes(&dynamodbtypes.TransactionCanceledException{}))
+	require.Equal(t, []string{"ResourceNotFoundException"}, errorCodes(fmt.Errorf("operation error, %w", &dynamodbtypes.ResourceNotFoundException{})))
+	require.Equal(t, []string{"CorruptData"}, errorCodes(withStatus(statusCorruptData, errors.New("checksum mismatch"))))
+	require.Equal(t, []string{"DeadlineExceeded"}, errorCodes(context.DeadlineExceeded))
+	require.Equal(t, []string{"Unknown"}, errorCodes(errors.New("unexpected")))
+}
+
 func setupSQSQueue(t *testing.T, ctx context.Context, awsConfig aws.Config, sqsEndpointResolver sqs.EndpointResolverV2) string {
 	sqsClient := sqs.NewFromConfig(awsConfig, sqs.WithEndpointResolverV2(sqsEndpointResolver))
 
@@ -406,9 +1036,14 @@ func setupSQSQueue(t *testing.T, ctx context.Context, awsConfig aws.Config, sqsE
 	sqsQueueURL := os.Getenv("SQS_QUEUE_URL")
 	queueName := sqsQueueURL[strings.LastIndex(sqsQueueURL, "/")+1:]
 
-	res, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
+	input := &sqs.CreateQueueInput{
 		QueueName: aws.String(queueName),
-	})
+	}
+	if strings.HasSuffix(queueName, ".fifo") {
+		input.Attributes = map[string]string{"FifoQueue": "true"}
+	}
+
+	res, err := sqsClient.CreateQueue(ctx, input)
 	require.NoError(t, err)
 	sqsQueueURL = *res.QueueUrl
 	t.Cleanup(func() {
@@ -423,6 +1058,163 @@ func setupSQSQueue(t *testing.T, ctx context.Context, awsConfig aws.Config, sqsE
 	return sqsQueueURL
 }
 
+func setupSNSTopic(t *testing.T, ctx context.Context, awsConfig aws.Config, snsEndpointResolver sns.EndpointResolverV2, sqsEndpointResolver sqs.EndpointResolverV2) (string, string) {
+	snsClient := sns.NewFromConfig(awsConfig, sns.WithEndpointResolverV2(snsEndpointResolver))
+	sqsClient := sqs.NewFromConfig(awsConfig, sqs.WithEndpointResolverV2(sqsEndpointResolver))
+
+	topic, err := snsClient.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name: aws.String("mytopic"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := snsClient.DeleteTopic(context.Background(), &sns.DeleteTopicInput{
+			TopicArn: topic.TopicArn,
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	queue, err := sqsClient.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String("mytopic-delivery"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := sqsClient.DeleteQueue(context.Background(), &sqs.DeleteQueueInput{
+			QueueUrl: queue.QueueUrl,
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	attrs, err := sqsClient.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queue.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+	})
+	require.NoError(t, err)
+
+	// The message is delivered in the SNS envelope, as raw message delivery is not enabled
+	_, err = snsClient.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: topic.TopicArn,
+		Protocol: aws.String("sqs"),
+		Endpoint: aws.String(attrs.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]),
+	})
+	require.NoError(t, err)
+
+	return *topic.TopicArn, *queue.QueueUrl
+}
+
+func setupKinesisStream(t *testing.T, ctx context.Context, awsConfig aws.Config, kinesisEndpointResolver kinesis.EndpointResolverV2) string {
+	kinesisClient := kinesis.NewFromConfig(awsConfig, kinesis.WithEndpointResolverV2(kinesisEndpointResolver))
+
+	streamName := "mystream"
+
+	_, err := kinesisClient.CreateStream(ctx, &kinesis.CreateStreamInput{
+		StreamName: &streamName,
+		ShardCount: aws.Int32(1),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := kinesisClient.DeleteStream(context.Background(), &kinesis.DeleteStreamInput{
+			StreamName:              &streamName,
+			EnforceConsumerDeletion: aws.Bool(true),
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	err = kinesis.NewStreamExistsWaiter(kinesisClient).Wait(ctx, &kinesis.DescribeStreamInput{
+		StreamName: &streamName,
+	}, 30*time.Second)
+	require.NoError(t, err)
+
+	return streamName
+}
+
+func setupKMSKey(t *testing.T, ctx context.Context, awsConfig aws.Config, kmsEndpointResolver kms.EndpointResolverV2) string {
+	kmsClient := kms.NewFromConfig(awsConfig, kms.WithEndpointResolverV2(kmsEndpointResolver))
+
+	res, err := kmsClient.CreateKey(ctx, &kms.CreateKeyInput{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := kmsClient.ScheduleKeyDeletion(context.Background(), &kms.ScheduleKeyDeletionInput{
+			KeyId:               res.KeyMetadata.KeyId,
+			PendingWindowInDays: aws.Int32(7),
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	// Use an alias as in the typical configuration
+	alias := "alias/aws-checker"
+	_, err = kmsClient.CreateAlias(ctx, &kms.CreateAliasInput{
+		AliasName:   &alias,
+		TargetKeyId: res.KeyMetadata.KeyId,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := kmsClient.DeleteAlias(context.Background(), &kms.DeleteAliasInput{
+			AliasName: &alias,
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	return alias
+}
+
+func setupSecretsManagerSecret(t *testing.T, ctx context.Context, awsConfig aws.Config, secretsManagerEndpointResolver secretsmanager.EndpointResolverV2) string {
+	secretsManagerClient := secretsmanager.NewFromConfig(awsConfig, secretsmanager.WithEndpointResolverV2(secretsManagerEndpointResolver))
+
+	secretName := "aws-checker/mysecret"
+
+	_, err := secretsManagerClient.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         &secretName,
+		SecretString: aws.String("hello"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := secretsManagerClient.DeleteSecret(context.Background(), &secretsmanager.DeleteSecretInput{
+			SecretId:                   &secretName,
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	return secretName
+}
+
+// setupSSMParameters creates a SecureString parameter under a path, and returns the name of it and the path.
+func setupSSMParameters(t *testing.T, ctx context.Context, awsConfig aws.Config, ssmEndpointResolver ssm.EndpointResolverV2) (string, string) {
+	ssmClient := ssm.NewFromConfig(awsConfig, ssm.WithEndpointResolverV2(ssmEndpointResolver))
+
+	path := "/aws-checker"
+	name := path + "/myparameter"
+
+	_, err := ssmClient.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:  &name,
+		Value: aws.String("hello"),
+		Type:  ssmtypes.ParameterTypeSecureString,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := ssmClient.DeleteParameter(context.Background(), &ssm.DeleteParameterInput{
+			Name: &name,
+		})
+		if err != nil {
+			t.Logf("Error: %v", err)
+		}
+	})
+
+	return name, path
+}
+
 func setupDynamoDBTable(t *testing.T, ctx context.Context, awsConfig aws.Config, dynamodbEndpointResolver dynamodb.EndpointResolverV2) {
 	dynamodbClient := dynamodb.NewFromConfig(awsConfig, dynamodb.WithEndpointResolverV2(dynamodbEndpointResolver))
 
@@ -430,20 +1222,57 @@ func setupDynamoDBTable(t *testing.T, ctx context.Context, awsConfig aws.Config,
 	// before running the tests.
 	dynamodbTable := os.Getenv("DYNAMODB_TABLE")
 
-	_, err := dynamodbClient.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: &dynamodbTable,
-		KeySchema: []dynamodbtypes.KeySchemaElement{
-			{
-				AttributeName: aws.String("id"),
-				KeyType:       dynamodbtypes.KeyTypeHash,
-			},
+	// The key schema follows the DYNAMODB_PARTITION_KEY* and DYNAMODB_SORT_KEY* environment variables
+	// set by the test case, if any.
+	keySchema := []dynamodbtypes.KeySchemaElement{
+		{
+			AttributeName: aws.String("id"),
+			KeyType:       dynamodbtypes.KeyTypeHash,
 		},
-		AttributeDefinitions: []dynamodbtypes.AttributeDefinition{
-			{
-				AttributeName: aws.String("id"),
-				AttributeType: dynamodbtypes.ScalarAttributeTypeS,
-			},
+	}
+	attributeDefinitions := []dynamodbtypes.AttributeDefinition{
+		{
+			AttributeName: aws.String("id"),
+			AttributeType: dynamodbtypes.ScalarAttributeTypeS,
 		},
+	}
+
+	if name := os.Getenv("DYNAMODB_PARTITION_KEY"); name != "" {
+		keySchema[0].AttributeName = aws.String(name)
+		attributeDefinitions[0].AttributeName = aws.String(name)
+	}
+	if typ := os.Getenv("DYNAMODB_PARTITION_KEY_TYPE"); typ != "" {
+		attributeDefinitions[0].AttributeType = dynamodbtypes.ScalarAttributeType(typ)
+	}
+
+	if name := os.Getenv("DYNAMODB_SORT_KEY"); name != "" {
+		typ := dynamodbtypes.ScalarAttributeTypeS
+		if v := os.Getenv("DYNAMODB_SORT_KEY_TYPE"); v != "" {
+			typ = dynamodbtypes.ScalarAttributeType(v)
+		}
+		keySchema = append(keySchema, dynamodbtypes.KeySchemaElement{
+			AttributeName: aws.String(name),
+			KeyType:       dynamodbtypes.KeyTypeRange,
+		})
+		attributeDefinitions = append(attributeDefinitions, dynamodbtypes.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: typ,
+		})
+	}
+
+	var streamSpecification *dynamodbtypes.StreamSpecification
+	if os.Getenv("DYNAMODB_STREAMS_CHECK") == "true" {
+		streamSpecification = &dynamodbtypes.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: dynamodbtypes.StreamViewTypeKeysOnly,
+		}
+	}
+
+	_, err := dynamodbClient.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            &dynamodbTable,
+		KeySchema:            keySchema,
+		AttributeDefinitions: attributeDefinitions,
+		StreamSpecification:  streamSpecification,
 		ProvisionedThroughput: &dynamodbtypes.ProvisionedThroughput{
 			ReadCapacityUnits:  aws.Int64(1),
 			WriteCapacityUnits: aws.Int64(1),
@@ -474,3 +1303,23 @@ func httpGetStr(t *testing.T, url string) (string, error) {
 
 	return string(body), nil
 }
+
+func TestParseProbeID(t *testing.T) {
+	c := &checker{instanceID: "aws-checker-2"}
+	id := c.probeID()
+
+	instanceID, at, ok := parseProbeID(id)
+	require.True(t, ok)
+	require.Equal(t, "aws-checker-2", instanceID)
+	require.WithinDuration(t, time.Now(), at, time.Minute)
+
+	instanceID, at, ok = parseProbeID("aws-checker-1700000000000000000-3")
+	require.True(t, ok)
+	require.Equal(t, "aws-checker", instanceID)
+	require.Equal(t, time.Unix(0, 1700000000000000000), at)
+
+	for _, id := range []string{"", "hello", "aws-checker-2", "-1700000000000000000-3", "aws-checker-now-3", "aws-checker-1700000000000000000-x"} {
+		_, _, ok := parseProbeID(id)
+		require.False(t, ok, id)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	webhookFormatJSON      = "json"
+	webhookFormatSlack     = "slack"
+	webhookFormatPagerDuty = "pagerduty"
+
+	defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+	// notifyQueueSize is the number of events waiting to be sent to the webhooks,
+	// beyond which new events are dropped.
+	notifyQueueSize = 100
+)
+
+// webhook is a single notification destination.
+type webhook struct {
+	url    string
+	format string
+	// routingKey is the integration key of the PagerDuty service.
+	// It is used only when format is webhookFormatPagerDuty.
+	routingKey string
+}
+
+// notifier calls webhooks when the health of an operation flips from healthy to unhealthy or back.
+//
+// Degraded operations, including flapping ones, are not notified so that transient failures do not page anyone.
+// At most one notification is sent per operation within the cooldown.
+// A state change that happens during the cooldown is notified once the cooldown has passed,
+// unless the operation has flipped back to the last notified state in the meantime.
+type notifier struct {
+	webhooks []webhook
+	cooldown time.Duration
+
+	source     string
+	httpClient *http.Client
+
+	// events is the queue of the events to be sent to the webhooks by the worker started by newNotifier.
+	events chan event
+
+	mu     sync.Mutex
+	states map[operationKey]*notifyState
+}
+
+type notifyState struct {
+	notifiedState string
+	notifiedAt    time.Time
+}
+
+// event is the state change of an operation sent to the webhooks.
+type event struct {
+	Service             string    `json:"service"`
+	Method              string    `json:"method"`
+	State               string    `json:"state"`
+	PreviousState       string    `json:"previous_state"`
+	Status              string    `json:"status"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Error               string    `json:"error,omitempty"`
+	Source              string    `json:"source"`
+	Time                time.Time `json:"time"`
+}
+
+// newNotifierFromEnv returns a notifier configured by the NOTIFY_* environment variables,
+// or nil if no webhook is configured.
+func newNotifierFromEnv() (*notifier, error) {
+	var webhooks []webhook
+
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		webhooks = append(webhooks, webhook{url: url, format: webhookFormatJSON})
+	}
+
+	if url := os.Getenv("NOTIFY_SLACK_WEBHOOK_URL"); url != "" {
+		webhooks = append(webhooks, webhook{url: url, format: webhookFormatSlack})
+	}
+
+	if key := os.Getenv("NOTIFY_PAGERDUTY_ROUTING_KEY"); key != "" {
+		url := os.Getenv("NOTIFY_PAGERDUTY_URL")
+		if url == "" {
+			url = defaultPagerDutyEventsURL
+		}
+		webhooks = append(webhooks, webhook{url: url, format: webhookFormatPagerDuty, routingKey: key})
+	}
+
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+
+	cooldown, err := envDuration("NOTIFY_COOLDOWN", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	return newNotifier(webhooks, cooldown), nil
+}
+
+func newNotifier(webhooks []webhook, cooldown time.Duration) *notifier {
+	source, err := os.Hostname()
+	if err != nil {
+		source = "aws-checker"
+	}
+
+	n := &notifier{
+		webhooks:   webhooks,
+		cooldown:   cooldown,
+		source:     source,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		events:     make(chan event, notifyQueueSize),
+		states:     make(map[operationKey]*notifyState),
+	}
+
+	go n.run()
+
+	return n
+}
+
+// update queues the event for the health of the operation after the result, if any.
+// The webhooks are called by the worker so that a slow webhook does not delay the checks.
+func (n *notifier) update(r result, h healthStatus) {
+	ev, ok := n.event(r, h)
+	if !ok {
+		return
+	}
+
+	select {
+	case n.events <- ev:
+	default:
+		log.Printf("too many notifications are pending, dropping the notification of %s %s being %s", ev.Service, ev.Method, ev.State)
+	}
+}
+
+// run sends the queued events to the webhooks one at a time, in the order they are queued.
+func (n *notifier) run() {
+	for ev := range n.events {
+		for _, w := range n.webhooks {
+			if err := n.send(w, ev); err != nil {
+				log.Printf("failed to notify %s webhook, %v", w.format, err)
+			}
+		}
+	}
+}
+
+// event returns the event to be notified for the health of the operation after the result, if any.
+func (n *notifier) event(r result, h healthStatus) (event, bool) {
+	if h.state == healthDegraded {
+		return event{}, false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := operationKey{service: r.service, method: r.method}
+	s, ok := n.states[key]
+	if !ok {
+		s = &notifyState{notifiedState: healthHealthy}
+		n.states[key] = s
+	}
+
+	if h.state == s.notifiedState {
+		return event{}, false
+	}
+
+	if !s.notifiedAt.IsZero() && r.time.Sub(s.notifiedAt) < n.cooldown {
+		return event{}, false
+	}
+
+	ev := event{
+		Service:             r.service,
+		Method:              r.method,
+		State:               h.state,
+		PreviousState:       s.notifiedState,
+		Status:              r.status,
+		ConsecutiveFailures: h.consecutiveFailures,
+		Source:              n.source,
+		Time:                r.time,
+	}
+	if r.err != nil {
+		ev.Error = r.err.Error()
+	}
+
+	s.notifiedState = h.state
+	s.notifiedAt = r.time
+
+	return ev, true
+}
+
+func (n *notifier) send(w webhook, ev event) error {
+	var payload any
+
+	switch w.format {
+	case webhookFormatSlack:
+		payload = slackPayload(ev)
+	case webhookFormatPagerDuty:
+		payload = pagerDutyPayload(w.routingKey, ev)
+	default:
+		payload = ev
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.httpClient.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (ev event) summary() string {
+	if ev.State == healthHealthy {
+		return fmt.Sprintf("%s %s has recovered", ev.Service, ev.Method)
+	}
+
+	return fmt.Sprintf("%s %s is unhealthy after %d consecutive failures: %s", ev.Service, ev.Method, ev.ConsecutiveFailures, ev.Error)
+}
+
+// slackPayload returns the payload for Slack incoming webhooks.
+func slackPayload(ev event) map[string]any {
+	icon := ":red_circle:"
+	if ev.State == healthHealthy {
+		icon = ":large_green_circle:"
+	}
+
+	return map[string]any{
+		"text": fmt.Sprintf("%s aws-checker on %s: %s", icon, ev.Source, ev.summary()),
+	}
+}
+
+// pagerDutyPayload returns the payload for the PagerDuty Events API v2.
+// The dedup key is derived from the operation so that the recovery resolves the alert triggered by the failure.
+func pagerDutyPayload(routingKey string, ev event) map[string]any {
+	p := map[string]any{
+		"routing_key":  routingKey,
+		"event_action": "trigger",
+		"dedup_key":    fmt.Sprintf("aws-checker/%s/%s/%s", ev.Source, ev.Service, ev.Method),
+	}
+
+	if ev.State == healthHealthy {
+		p["event_action"] = "resolve"
+		return p
+	}
+
+	p["payload"] = map[string]any{
+		"summary":        ev.summary(),
+		"source":         ev.Source,
+		"severity":       "error",
+		"timestamp":      ev.Time.Format(time.RFC3339),
+		"component":      ev.Service,
+		"group":          ev.Method,
+		"custom_details": ev,
+	}
+
+	return p
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	type request struct {
+		contentType string
+		body        []byte
+	}
+
+	requests := make(chan request, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		requests <- request{contentType: r.Header.Get("Content-Type"), body: body}
+	}))
+	defer srv.Close()
+
+	// receive waits for the next n notifications, which are sent in order by the worker of the notifier
+	receive := func(n int) []map[string]any {
+		t.Helper()
+
+		var bodies []map[string]any
+		for i := 0; i < n; i++ {
+			select {
+			case r := <-requests:
+				require.Equal(t, "application/json", r.contentType)
+
+				var m map[string]any
+				require.NoError(t, json.Unmarshal(r.body, &m))
+				bodies = append(bodies, m)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for notification %d of %d", i+1, n)
+			}
+		}
+
+		return bodies
+	}
+
+	n := newNotifier([]webhook{
+		{url: srv.URL, format: webhookFormatJSON},
+		{url: srv.URL, format: webhookFormatSlack},
+		{url: srv.URL, format: webhookFormatPagerDuty, routingKey: "mykey"},
+	}, time.Minute)
+	n.source = "myhost"
+
+	h, err := newHealth(1, 2, 20, 0.5)
+	require.NoError(t, err)
+	h.listeners = append(h.listeners, n)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	observe := func(offset time.Duration, err error) {
+		r := result{service: "S3", method: "GetObject", status: "Success", err: err, time: start.Add(offset)}
+		if err != nil {
+			r.status = "Failure"
+		}
+		h.observe(r)
+	}
+
+	// A degraded operation must not be notified, so the first notifications are the ones of the unhealthy operation
+	observe(0, errors.New("access denied"))
+	observe(time.Second, errors.New("access denied"))
+	bodies := receive(3)
+
+	require.Equal(t, "S3", bodies[0]["service"])
+	require.Equal(t, "GetObject", bodies[0]["method"])
+	require.Equal(t, "unhealthy", bodies[0]["state"])
+	require.Equal(t, "healthy", bodies[0]["previous_state"])
+	require.Equal(t, "Failure", bodies[0]["status"])
+	require.Equal(t, float64(2), bodies[0]["consecutive_failures"])
+	require.Equal(t, "access denied", bodies[0]["error"])
+	require.Equal(t, start.Add(time.Second).Format(time.RFC3339), bodies[0]["time"])
+
+	require.Equal(t, ":red_circle: aws-checker on myhost: S3 GetObject is unhealthy after 2 consecutive failures: access denied", bodies[1]["text"])
+
+	require.Equal(t, "mykey", bodies[2]["routing_key"])
+	require.Equal(t, "trigger", bodies[2]["event_action"])
+	require.Equal(t, "aws-checker/myhost/S3/GetObject", bodies[2]["dedup_key"])
+	require.Equal(t, "error", bodies[2]["payload"].(map[string]any)["severity"])
+
+	// The recovery within the cooldown must be deferred until the cooldown has passed
+	observe(2*time.Second, nil)
+	observe(time.Minute+time.Second, nil)
+	bodies = receive(3)
+
+	require.Equal(t, "healthy", bodies[0]["state"])
+	require.Equal(t, "unhealthy", bodies[0]["previous_state"])
+	require.Equal(t, start.Add(time.Minute+time.Second).Format(time.RFC3339), bodies[0]["time"])
+	require.Equal(t, ":large_green_circle: aws-checker on myhost: S3 GetObject has recovered", bodies[1]["text"])
+	require.Equal(t, "resolve", bodies[2]["event_action"])
+	require.Equal(t, "aws-checker/myhost/S3/GetObject", bodies[2]["dedup_key"])
+	require.NotContains(t, bodies[2], "payload")
+
+	// A flip back to the notified state within the cooldown must not be notified,
+	// so the next notifications are the ones of the failures after the cooldown
+	observe(time.Minute+2*time.Second, errors.New("access denied"))
+	observe(time.Minute+3*time.Second, errors.New("access denied"))
+	observe(time.Minute+4*time.Second, nil)
+	observe(3*time.Minute, nil)
+	observe(4*time.Minute, errors.New("access denied"))
+	observe(4*time.Minute+time.Second, errors.New("access denied"))
+	bodies = receive(3)
+
+	require.Equal(t, "unhealthy", bodies[0]["state"])
+	require.Equal(t, start.Add(4*time.Minute+time.Second).Format(time.RFC3339), bodies[0]["time"])
+}
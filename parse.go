@@ -3,6 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
+	"time"
 )
 
 func parseFlags(args []string) ([]Option, *int) {
@@ -40,3 +43,51 @@ func parseFlags(args []string) ([]Option, *int) {
 
 	return nil, &code
 }
+
+// envInt returns the integer value of the environment variable name,
+// or def when it is unset or empty.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q, %v", name, v, err)
+	}
+
+	return i, nil
+}
+
+// envDuration returns the duration value of the environment variable name,
+// or def when it is unset or empty.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q, %v", name, v, err)
+	}
+
+	return d, nil
+}
+
+// envBool returns the boolean value of the environment variable name,
+// or false when it is unset or empty.
+func envBool(name string) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q, %v", name, v, err)
+	}
+
+	return b, nil
+}
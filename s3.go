@@ -0,0 +1,775 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// s3MaxProbeObjects is the maximum number of probe objects tracked for the cleanup on shutdown.
+// When a long outage keeps the checks from deleting their probe objects, the oldest ones are no longer tracked,
+// so that the cleanup neither grows without bound nor outlasts its timeout.
+const s3MaxProbeObjects = 100
+
+// statusCorruptData is the status of a request that succeeded but returned content different from the expected one.
+const statusCorruptData = "CorruptData"
+
+// statusTimeout is the status of a check that timed out waiting for a write to show up,
+// like a replicated object or a record read back from a stream.
+const statusTimeout = "Timeout"
+
+// configureS3 configures the optional S3 checks from the environment variables.
+func (c *checker) configureS3(cfg aws.Config) error {
+	c.s3ProbePrefix = os.Getenv("S3_PROBE_PREFIX")
+	c.s3ExpectedSHA256 = os.Getenv("S3_EXPECTED_SHA256")
+	c.s3ExpectedETag = os.Getenv("S3_EXPECTED_ETAG")
+	c.s3ProbeObjects = make(map[string]time.Time)
+
+	var err error
+
+	c.s3VerifyContent, err = envBool("S3_VERIFY_CONTENT")
+	if err != nil {
+		return err
+	}
+
+	c.s3ProbeObjectSize, err = envInt("S3_PROBE_OBJECT_SIZE", 1024)
+	if err != nil {
+		return err
+	}
+
+	c.s3MultipartParts, err = envInt("S3_MULTIPART_PARTS", 0)
+	if err != nil {
+		return err
+	}
+
+	// 5 MiB is the minimum size of a part other than the last one
+	c.s3MultipartPartSize, err = envInt("S3_MULTIPART_PART_SIZE", 5*1024*1024)
+	if err != nil {
+		return err
+	}
+
+	c.s3MultipartInterval, err = envDuration("S3_MULTIPART_INTERVAL", time.Minute)
+	if err != nil {
+		return err
+	}
+
+	if c.s3MultipartParts > 0 && c.s3ProbePrefix == "" {
+		return fmt.Errorf("S3_PROBE_PREFIX is required for S3_MULTIPART_PARTS")
+	}
+
+	c.s3PresignedCheck, err = envBool("S3_PRESIGNED_CHECK")
+	if err != nil {
+		return err
+	}
+
+	if c.s3PresignedCheck && c.s3ProbePrefix == "" {
+		return fmt.Errorf("S3_PROBE_PREFIX is required for S3_PRESIGNED_CHECK")
+	}
+
+	c.s3ReplicationBucket = os.Getenv("S3_REPLICATION_DESTINATION_BUCKET")
+	if c.s3ReplicationBucket != "" {
+		if c.s3ProbePrefix == "" {
+			return fmt.Errorf("S3_PROBE_PREFIX is required for S3_REPLICATION_DESTINATION_BUCKET")
+		}
+
+		s3ReplicationOpts := append([]func(*s3.Options){}, c.s3Opts...)
+		if region := os.Getenv("S3_REPLICATION_DESTINATION_REGION"); region != "" {
+			s3ReplicationOpts = append(s3ReplicationOpts, func(o *s3.Options) {
+				o.Region = region
+			})
+		}
+		c.s3ReplicationClient = s3.NewFromConfig(cfg, s3ReplicationOpts...)
+
+		c.s3ReplicationTimeout, err = envDuration("S3_REPLICATION_TIMEOUT", 15*time.Minute)
+		if err != nil {
+			return err
+		}
+
+		c.s3ReplicationPollInterval, err = envDuration("S3_REPLICATION_POLL_INTERVAL", 5*time.Second)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.s3ReplicationLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "aws_s3_replication_lag_seconds",
+			Help:    "Time until an object written to the source bucket shows up in the destination bucket.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+		[]string{"status"},
+	)
+
+	c.s3DownloadThroughput = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_s3_download_throughput_bytes_per_second",
+			Help: "Throughput of the latest S3 object download, from sending the request to reading the whole body.",
+		},
+		[]string{"method"},
+	)
+
+	return nil
+}
+
+func (c *checker) doCheckS3(ctx context.Context) {
+	operations := []operation{
+		{
+			method: "GetObject",
+			operations: []func() error{
+				func() error {
+					start := time.Now()
+					res, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &c.s3Key,
+					})
+					if err != nil {
+						return err
+					}
+					defer res.Body.Close()
+
+					if !c.s3VerifyContent {
+						return nil
+					}
+
+					content, err := c.readS3Object("GetObject", res.Body, start)
+					if err != nil {
+						return err
+					}
+
+					return c.verifyS3Object(content, aws.ToString(res.ETag))
+				},
+			},
+		},
+	}
+
+	if c.s3ProbePrefix != "" {
+		operations = append(operations, c.s3WriteOperations(ctx)...)
+	}
+
+	if c.s3PresignedCheck {
+		operations = append(operations, c.s3PresignedOperations(ctx)...)
+	}
+
+	c.doCheckService(ctx, "S3", operations)
+}
+
+// doCheckS3Multipart uploads a probe object in parts, and deletes it.
+// It runs at its own interval, as it uploads S3_MULTIPART_PARTS parts of S3_MULTIPART_PART_SIZE bytes every time.
+func (c *checker) doCheckS3Multipart(ctx context.Context) {
+	ops, abort := c.s3MultipartOperations(ctx)
+	// Deferred so that the upload is aborted even when the check is canceled on shutdown
+	defer abort()
+
+	c.doCheckService(ctx, "S3", ops)
+}
+
+// s3WriteOperations returns the operations to write a unique probe object, head it, list it,
+// read it back, and delete it.
+// The reads are skipped when the write failed, as there is nothing to read,
+// but the deletion runs anyway, so that the probe object is deleted whenever possible.
+func (c *checker) s3WriteOperations(ctx context.Context) []operation {
+	var (
+		key     = c.s3ProbePrefix + c.probeID()
+		written bool
+	)
+
+	content := randomContent(c.s3ProbeObjectSize)
+
+	return []operation{
+		{
+			method: "PutObject",
+			operations: []func() error{
+				func() error {
+					c.trackS3ProbeObjects(key)
+
+					_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+						Body:   bytes.NewReader(content),
+					})
+					if err != nil {
+						return err
+					}
+
+					written = true
+					return nil
+				},
+			},
+		},
+		{
+			method: "HeadObject",
+			operations: []func() error{
+				func() error {
+					if !written {
+						return errSkipOperation
+					}
+
+					_, err := c.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+					})
+					return err
+				},
+			},
+		},
+		{
+			method: "ListObjectsV2",
+			operations: []func() error{
+				func() error {
+					if !written {
+						return errSkipOperation
+					}
+
+					res, err := c.s3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+						Bucket:  &c.s3Bucket,
+						Prefix:  &key,
+						MaxKeys: aws.Int32(1),
+					})
+					if err != nil {
+						return err
+					}
+					if len(res.Contents) == 0 {
+						return fmt.Errorf("object %s is not listed", key)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "GetProbeObject",
+			operations: []func() error{
+				func() error {
+					if !written {
+						return errSkipOperation
+					}
+
+					start := time.Now()
+					res, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+					})
+					if err != nil {
+						return err
+					}
+					defer res.Body.Close()
+
+					got, err := c.readS3Object("GetProbeObject", res.Body, start)
+					if err != nil {
+						return err
+					}
+
+					if !bytes.Equal(got, content) {
+						return withStatus(statusCorruptData, fmt.Errorf("content of object %s differs from the written one: got %d bytes, want %d bytes", key, len(got), len(content)))
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "DeleteObject",
+			operations: []func() error{
+				func() error {
+					return c.deleteS3ProbeObject(ctx, key)
+				},
+			},
+		},
+	}
+}
+
+// s3MultipartOperations returns the operations to upload a probe object in parts, and delete it.
+//
+// The other steps are skipped when the upload has not been created.
+//
+// The returned abort function aborts the upload unless it has been completed,
+// so that incomplete uploads are not left behind on failure or shutdown.
+// A completed object is deleted by the cleanup on shutdown unless deleted by the check.
+func (c *checker) s3MultipartOperations(ctx context.Context) ([]operation, func()) {
+	var (
+		key       = c.s3ProbePrefix + c.probeID()
+		uploadID  *string
+		parts     []s3types.CompletedPart
+		completed bool
+	)
+
+	content := randomContent(c.s3MultipartPartSize)
+
+	var uploadParts []func() error
+	for i := 1; i <= c.s3MultipartParts; i++ {
+		partNumber := int32(i)
+		uploadParts = append(uploadParts, func() error {
+			if uploadID == nil {
+				return errSkipOperation
+			}
+
+			res, err := c.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     &c.s3Bucket,
+				Key:        &key,
+				UploadId:   uploadID,
+				PartNumber: &partNumber,
+				Body:       bytes.NewReader(content),
+			})
+			if err != nil {
+				return err
+			}
+
+			parts = append(parts, s3types.CompletedPart{
+				PartNumber:    &partNumber,
+				ETag:          res.ETag,
+				ChecksumCRC32: res.ChecksumCRC32,
+			})
+			return nil
+		})
+	}
+
+	operations := []operation{
+		{
+			method: "CreateMultipartUpload",
+			operations: []func() error{
+				func() error {
+					res, err := c.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+					})
+					if err != nil {
+						return err
+					}
+
+					uploadID = res.UploadId
+					return nil
+				},
+			},
+		},
+		{
+			method:     "UploadPart",
+			operations: uploadParts,
+		},
+		{
+			method: "CompleteMultipartUpload",
+			operations: []func() error{
+				func() error {
+					if uploadID == nil {
+						return errSkipOperation
+					}
+
+					_, err := c.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+						Bucket:   &c.s3Bucket,
+						Key:      &key,
+						UploadId: uploadID,
+						MultipartUpload: &s3types.CompletedMultipartUpload{
+							Parts: parts,
+						},
+					})
+					if err != nil {
+						return err
+					}
+
+					completed = true
+					c.trackS3ProbeObjects(key)
+					return nil
+				},
+			},
+		},
+		{
+			method: "DeleteMultipartObject",
+			operations: []func() error{
+				func() error {
+					// There is no object to delete unless the upload has been completed
+					if !completed {
+						return errSkipOperation
+					}
+
+					return c.deleteS3ProbeObject(ctx, key)
+				},
+			},
+		},
+	}
+
+	abort := func() {
+		if uploadID == nil || completed {
+			return
+		}
+
+		abortCtx, cancel := cleanupContext()
+		defer cancel()
+
+		start := time.Now()
+		_, err := c.s3Client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   &c.s3Bucket,
+			Key:      &key,
+			UploadId: uploadID,
+		})
+		if err != nil {
+			log.Printf("failed to AbortMultipartUpload, %v", err)
+		}
+		c.observe("S3", "AbortMultipartUpload", time.Since(start), err)
+	}
+
+	return operations, abort
+}
+
+// s3PresignedOperations returns the operations to put and get a probe object via presigned URLs,
+// sent over plain HTTP without the SDK signing the requests, and delete it.
+// This catches the failures specific to presigned URLs, like clock skews and endpoint policies.
+// The rest is skipped when the PUT failed, and the cleanup on shutdown deletes the object in case it has been written.
+func (c *checker) s3PresignedOperations(ctx context.Context) []operation {
+	var (
+		key     = c.s3ProbePrefix + c.probeID()
+		presign = s3.NewPresignClient(c.s3Client)
+		written bool
+	)
+
+	content := randomContent(c.s3ProbeObjectSize)
+
+	return []operation{
+		{
+			method: "PresignedPutObject",
+			operations: []func() error{
+				func() error {
+					req, err := presign.PresignPutObject(ctx, &s3.PutObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+					})
+					if err != nil {
+						return err
+					}
+
+					c.trackS3ProbeObjects(key)
+
+					if _, err := c.doPresignedRequest(ctx, req, content); err != nil {
+						// Nothing has been written when S3 rejected the request
+						var reqErr *presignedRequestError
+						if errors.As(err, &reqErr) && reqErr.statusCode/100 == 4 {
+							c.untrackS3ProbeObjects(key)
+						}
+						return err
+					}
+
+					written = true
+					return nil
+				},
+			},
+		},
+		{
+			method: "PresignedGetObject",
+			operations: []func() error{
+				func() error {
+					if !written {
+						return errSkipOperation
+					}
+
+					req, err := presign.PresignGetObject(ctx, &s3.GetObjectInput{
+						Bucket: &c.s3Bucket,
+						Key:    &key,
+					})
+					if err != nil {
+						return err
+					}
+
+					got, err := c.doPresignedRequest(ctx, req, nil)
+					if err != nil {
+						return err
+					}
+
+					if !bytes.Equal(got, content) {
+						return withStatus(statusCorruptData, fmt.Errorf("content of object %s differs from the written one: got %d bytes, want %d bytes", key, len(got), len(content)))
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "DeletePresignedObject",
+			operations: []func() error{
+				func() error {
+					if !written {
+						return errSkipOperation
+					}
+
+					return c.deleteS3ProbeObject(ctx, key)
+				},
+			},
+		},
+	}
+}
+
+// doPresignedRequest sends the presigned request with the body over plain HTTP,
+// and returns the response body.
+func (c *checker) doPresignedRequest(ctx context.Context, presigned *v4.PresignedHTTPRequest, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, presigned.Method, presigned.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range presigned.SignedHeader {
+		// The Host header is set by net/http from the URL
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		req.Header[name] = values
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode/100 != 2 {
+		return nil, &presignedRequestError{method: presigned.Method, statusCode: res.StatusCode, body: resBody}
+	}
+
+	return resBody, nil
+}
+
+// presignedRequestError is the error of a presigned request that S3 responded to with a status code other than 2xx.
+type presignedRequestError struct {
+	method     string
+	statusCode int
+	body       []byte
+}
+
+func (e *presignedRequestError) Error() string {
+	return fmt.Sprintf("presigned %s request failed with status code %d: %s", e.method, e.statusCode, e.body)
+}
+
+// errS3ReplicationTimeout is returned when the replicated object did not show up within the timeout.
+var errS3ReplicationTimeout = errors.New("timed out waiting for the object to be replicated")
+
+// doCheckS3Replication writes a timestamped probe object to the source bucket,
+// and polls the destination bucket until the object shows up, to measure the replication lag.
+func (c *checker) doCheckS3Replication(ctx context.Context) {
+	key := c.s3ProbePrefix + c.probeID()
+
+	putStart := time.Now()
+	_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &c.s3Bucket,
+		Key:    &key,
+		Body:   strings.NewReader(putStart.Format(time.RFC3339Nano)),
+	})
+	putDuration := time.Since(putStart)
+	if err == nil {
+		// Deleted even when the check is canceled right after the object has been written
+		defer c.deleteS3ReplicationObjects(key)
+	}
+
+	if ctx.Err() == context.Canceled {
+		log.Printf("context is canceled")
+		return
+	} else if err != nil {
+		log.Printf("failed to put replication object, %v", err)
+	}
+	c.observe("S3", "PutReplicationObject", putDuration, err)
+	if err != nil {
+		return
+	}
+
+	written := time.Now()
+	err = c.waitS3Replication(ctx, key)
+	lag := time.Since(written)
+
+	status := "Success"
+	if ctx.Err() == context.Canceled {
+		log.Printf("context is canceled")
+		return
+	} else if errors.Is(err, errS3ReplicationTimeout) {
+		log.Printf("failed to replicate object %s, %v", key, err)
+		status = statusTimeout
+		err = withStatus(status, err)
+	} else if err != nil {
+		log.Printf("failed to replicate object %s, %v", key, err)
+		status = "Failure"
+	}
+	c.s3ReplicationLag.WithLabelValues(status).Observe(lag.Seconds())
+	c.observe("S3", "Replication", lag, err)
+}
+
+// waitS3Replication polls the destination bucket until the object shows up,
+// or the replication timeout passes.
+func (c *checker) waitS3Replication(ctx context.Context, key string) error {
+	timeout := time.After(c.s3ReplicationTimeout)
+
+	for {
+		_, err := c.s3ReplicationClient.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &c.s3ReplicationBucket,
+			Key:    &key,
+		})
+		if err == nil {
+			return nil
+		}
+
+		// S3 responds with 403 instead of 404 without s3:ListBucket on the destination bucket,
+		// which is considered a failure rather than the object being not replicated yet.
+		var notFound *s3types.NotFound
+		if !errors.As(err, &notFound) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return errS3ReplicationTimeout
+		case <-time.After(c.s3ReplicationPollInterval):
+		}
+	}
+}
+
+// deleteS3ReplicationObjects deletes the probe object from both of the source and destination buckets.
+// The deletion is not replicated unless the replication rule enables delete marker replication.
+func (c *checker) deleteS3ReplicationObjects(key string) {
+	ctx, cancel := cleanupContext()
+	defer cancel()
+
+	if _, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &c.s3Bucket,
+		Key:    &key,
+	}); err != nil {
+		log.Printf("failed to delete replication object from the source bucket, %v", err)
+	}
+
+	if _, err := c.s3ReplicationClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &c.s3ReplicationBucket,
+		Key:    &key,
+	}); err != nil {
+		log.Printf("failed to delete replication object from the destination bucket, %v", err)
+	}
+}
+
+// deleteS3ProbeObject deletes the probe object with the key.
+// Deleting an object that does not exist succeeds, so it also stops tracking the probe objects never written.
+func (c *checker) deleteS3ProbeObject(ctx context.Context, key string) error {
+	_, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &c.s3Bucket,
+		Key:    &key,
+	})
+	if err == nil {
+		c.untrackS3ProbeObjects(key)
+	}
+	return err
+}
+
+// trackS3ProbeObjects records the keys of the probe objects about to be written,
+// so that they are deleted by the cleanup on shutdown unless deleted by the check.
+// Up to s3MaxProbeObjects keys are tracked, and the oldest ones are dropped beyond that.
+func (c *checker) trackS3ProbeObjects(keys ...string) {
+	c.trackS3ProbeObjectsAt(time.Now(), keys...)
+}
+
+// trackS3ProbeObjectsAt is trackS3ProbeObjects with the time the probe objects are written at,
+// which decides the oldest ones to drop.
+func (c *checker) trackS3ProbeObjectsAt(now time.Time, keys ...string) {
+	c.s3ProbeObjectsMu.Lock()
+	defer c.s3ProbeObjectsMu.Unlock()
+
+	for _, key := range keys {
+		c.s3ProbeObjects[key] = now
+	}
+
+	for len(c.s3ProbeObjects) > s3MaxProbeObjects {
+		var (
+			oldest   string
+			oldestAt time.Time
+		)
+		for key, at := range c.s3ProbeObjects {
+			if oldest == "" || at.Before(oldestAt) {
+				oldest, oldestAt = key, at
+			}
+		}
+
+		log.Printf("too many S3 probe objects are left behind, probe object %s will not be cleaned up", oldest)
+		delete(c.s3ProbeObjects, oldest)
+	}
+}
+
+// untrackS3ProbeObjects forgets the keys of the probe objects that have been deleted.
+func (c *checker) untrackS3ProbeObjects(keys ...string) {
+	c.s3ProbeObjectsMu.Lock()
+	defer c.s3ProbeObjectsMu.Unlock()
+
+	for _, key := range keys {
+		delete(c.s3ProbeObjects, key)
+	}
+}
+
+// cleanupS3ProbeObjects deletes the probe objects left behind by the checks that were stopped halfway.
+func (c *checker) cleanupS3ProbeObjects(ctx context.Context) {
+	c.s3ProbeObjectsMu.Lock()
+	keys := make([]string, 0, len(c.s3ProbeObjects))
+	for key := range c.s3ProbeObjects {
+		keys = append(keys, key)
+	}
+	c.s3ProbeObjectsMu.Unlock()
+
+	for i, key := range keys {
+		if ctx.Err() != nil {
+			log.Printf("failed to clean up %d S3 probe objects, %v", len(keys)-i, ctx.Err())
+			return
+		}
+
+		if err := c.deleteS3ProbeObject(ctx, key); err != nil {
+			log.Printf("failed to clean up S3 probe object %s, %v", key, err)
+		}
+	}
+}
+
+// randomContent returns n random bytes for the content of probe objects.
+func randomContent(n int) []byte {
+	content := make([]byte, n)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(content)
+	return content
+}
+
+// readS3Object reads the whole object body and records the download throughput,
+// measured from start, which is when the request was sent.
+func (c *checker) readS3Object(method string, body io.Reader, start time.Time) ([]byte, error) {
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		c.s3DownloadThroughput.WithLabelValues(method).Set(float64(len(content)) / elapsed)
+	}
+
+	return content, nil
+}
+
+// verifyS3Object compares the content and the ETag of the S3_KEY object to the expected ones, if any.
+func (c *checker) verifyS3Object(content []byte, etag string) error {
+	if c.s3ExpectedSHA256 != "" {
+		sum := sha256.Sum256(content)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, c.s3ExpectedSHA256) {
+			return withStatus(statusCorruptData, fmt.Errorf("sha256 of object %s is %s, expected %s", c.s3Key, got, c.s3ExpectedSHA256))
+		}
+	}
+
+	if c.s3ExpectedETag != "" {
+		if got, want := strings.Trim(etag, `"`), strings.Trim(c.s3ExpectedETag, `"`); got != want {
+			return withStatus(statusCorruptData, fmt.Errorf("ETag of object %s is %s, expected %s", c.s3Key, got, want))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackS3ProbeObjects(t *testing.T) {
+	c := &checker{s3ProbeObjects: make(map[string]time.Time)}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.trackS3ProbeObjectsAt(now, "first")
+	for i := 0; i < s3MaxProbeObjects; i++ {
+		c.trackS3ProbeObjectsAt(now.Add(time.Duration(i+1)*time.Second), fmt.Sprintf("object-%d", i))
+	}
+	require.Len(t, c.s3ProbeObjects, s3MaxProbeObjects)
+	require.NotContains(t, c.s3ProbeObjects, "first")
+
+	c.untrackS3ProbeObjects("object-0", "object-1")
+	require.Len(t, c.s3ProbeObjects, s3MaxProbeObjects-2)
+	require.NotContains(t, c.s3ProbeObjects, "object-0")
+}
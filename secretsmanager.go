@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// configureSecretsManager configures the Secrets Manager check from the environment variables.
+func (c *checker) configureSecretsManager(cfg aws.Config) error {
+	c.secretsManagerSecretID = os.Getenv("SECRETS_MANAGER_SECRET_ID")
+	if c.secretsManagerSecretID == "" {
+		return nil
+	}
+
+	c.secretsManagerClient = secretsmanager.NewFromConfig(cfg, c.secretsManagerOpts...)
+	c.secretsManagerExpectedVersionID = os.Getenv("SECRETS_MANAGER_EXPECTED_VERSION_ID")
+	c.secretsManagerExpectedSHA256 = os.Getenv("SECRETS_MANAGER_EXPECTED_SHA256")
+
+	return nil
+}
+
+// doCheckSecretsManager reads the secret value, and compares its version ID and hash to the expected ones, if any.
+func (c *checker) doCheckSecretsManager(ctx context.Context) {
+	c.doCheckService(ctx, "SecretsManager", []operation{
+		{
+			method: "GetSecretValue",
+			operations: []func() error{
+				func() error {
+					res, err := c.secretsManagerClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+						SecretId: &c.secretsManagerSecretID,
+					})
+					if err != nil {
+						return err
+					}
+
+					if c.secretsManagerExpectedVersionID != "" && aws.ToString(res.VersionId) != c.secretsManagerExpectedVersionID {
+						return withStatus(statusCorruptData, fmt.Errorf("version ID of secret %s is %s, expected %s", c.secretsManagerSecretID, aws.ToString(res.VersionId), c.secretsManagerExpectedVersionID))
+					}
+
+					value := res.SecretBinary
+					if res.SecretString != nil {
+						value = []byte(*res.SecretString)
+					}
+					if !secretSHA256Equal(value, c.secretsManagerExpectedSHA256) {
+						return withStatus(statusCorruptData, fmt.Errorf("sha256 of secret %s does not match the expected one", c.secretsManagerSecretID))
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+// secretSHA256Equal returns true if the hex-encoded SHA-256 digest of the secret value is equal to the expected one,
+// or the expected one is empty.
+//
+// The digest is never returned, as even a digest of a weak secret can be brute-forced.
+func secretSHA256Equal(value []byte, expected string) bool {
+	if expected == "" {
+		return true
+	}
+
+	sum := sha256.Sum256(value)
+	return strings.EqualFold(hex.EncodeToString(sum[:]), expected)
+}
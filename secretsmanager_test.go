@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretSHA256Equal(t *testing.T) {
+	require.True(t, secretSHA256Equal([]byte("hello"), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+	require.True(t, secretSHA256Equal([]byte("hello"), "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"))
+	require.False(t, secretSHA256Equal([]byte("hello!"), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+	require.True(t, secretSHA256Equal([]byte("anything"), ""))
+}
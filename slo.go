@@ -0,0 +1,407 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sloResolution is the width of the time buckets results are counted in.
+// Compliance is computed over whole buckets, so windows shorter than a few buckets are imprecise.
+const sloResolution = time.Minute
+
+// sloCoarseResolution is the width of the time buckets for windows longer than sloCoarseWindow,
+// to keep the number of buckets small for windows like 30d.
+const (
+	sloCoarseResolution = time.Hour
+	sloCoarseWindow     = 24 * time.Hour
+)
+
+// sloObjective is the availability and optional latency objective for an operation.
+type sloObjective struct {
+	service string
+	// method is the method the objective applies to, or "*" for all methods of the service.
+	method string
+	// target is the ratio of good results to all results, like 0.999.
+	target float64
+	// latency is the maximum duration of a good result.
+	// Zero means that any successful result is good.
+	latency time.Duration
+}
+
+func (o sloObjective) good(r result) bool {
+	return r.err == nil && (o.latency == 0 || r.duration <= o.latency)
+}
+
+type sloWindow struct {
+	name     string
+	duration time.Duration
+}
+
+// slo tracks the compliance of operations to their objectives over several windows,
+// and exposes it as gauges.
+type slo struct {
+	objectives []sloObjective
+	// windows is sorted by duration. The longest window is the SLO period the error budget is computed for.
+	windows []sloWindow
+
+	mu     sync.Mutex
+	series map[operationKey]*sloSeries
+
+	now func() time.Time
+
+	objectiveDesc  *prometheus.Desc
+	complianceDesc *prometheus.Desc
+	burnRateDesc   *prometheus.Desc
+	budgetDesc     *prometheus.Desc
+}
+
+// sloSeries counts the results of an operation in every window.
+type sloSeries struct {
+	objective sloObjective
+	// counters are in the same order as the windows of the slo.
+	counters []*sloCounter
+}
+
+// sloCounter is a ring buffer of result counts per resolution, covering a window.
+// It keeps the running totals of the buckets, so that reading them does not need to sum up the buckets.
+type sloCounter struct {
+	resolution time.Duration
+	buckets    []sloBucket
+	// latest is the latest slot, identifying a time range of the resolution, the buckets cover.
+	latest      int64
+	total, good int
+}
+
+type sloBucket struct {
+	total, good int
+}
+
+func newSLOCounter(window time.Duration) *sloCounter {
+	resolution := sloResolution
+	if window > sloCoarseWindow {
+		resolution = sloCoarseResolution
+	}
+
+	return &sloCounter{
+		resolution: resolution,
+		buckets:    make([]sloBucket, int((window+resolution-1)/resolution)),
+	}
+}
+
+// advance moves the ring to the slot of t, expiring the buckets that fall out of the window.
+func (c *sloCounter) advance(t time.Time) {
+	slot := t.UnixNano() / int64(c.resolution)
+	if slot <= c.latest {
+		return
+	}
+
+	n := int64(len(c.buckets))
+	if slot-c.latest >= n {
+		clear(c.buckets)
+		c.total, c.good = 0, 0
+	} else {
+		for s := c.latest + 1; s <= slot; s++ {
+			b := &c.buckets[s%n]
+			c.total -= b.total
+			c.good -= b.good
+			*b = sloBucket{}
+		}
+	}
+	c.latest = slot
+}
+
+// add counts a result at t, unless it is older than the window.
+func (c *sloCounter) add(t time.Time, good bool) {
+	c.advance(t)
+
+	slot := t.UnixNano() / int64(c.resolution)
+	n := int64(len(c.buckets))
+	if slot <= c.latest-n {
+		return
+	}
+
+	b := &c.buckets[slot%n]
+	b.total++
+	c.total++
+	if good {
+		b.good++
+		c.good++
+	}
+}
+
+// sloReport is the compliance of an operation to its objective.
+type sloReport struct {
+	Service                 string            `json:"service"`
+	Method                  string            `json:"method"`
+	Objective               float64           `json:"objective"`
+	LatencyThresholdSeconds float64           `json:"latency_threshold_seconds,omitempty"`
+	Windows                 []sloWindowReport `json:"windows"`
+	ErrorBudgetRemaining    *float64          `json:"error_budget_remaining,omitempty"`
+}
+
+type sloWindowReport struct {
+	Window     string  `json:"window"`
+	Total      int     `json:"total"`
+	Good       int     `json:"good"`
+	Compliance float64 `json:"compliance"`
+	BurnRate   float64 `json:"burn_rate"`
+}
+
+// newSLOFromEnv returns the slo configured by SLO_OBJECTIVES and SLO_WINDOWS,
+// or nil if no objective is configured.
+func newSLOFromEnv() (*slo, error) {
+	v := os.Getenv("SLO_OBJECTIVES")
+	if v == "" {
+		return nil, nil
+	}
+
+	objectives, err := parseSLOObjectives(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLO_OBJECTIVES %q, %v", v, err)
+	}
+
+	w := os.Getenv("SLO_WINDOWS")
+	if w == "" {
+		w = "1h,6h,1d,30d"
+	}
+
+	windows, err := parseSLOWindows(w)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLO_WINDOWS %q, %v", w, err)
+	}
+
+	return newSLO(objectives, windows), nil
+}
+
+func newSLO(objectives []sloObjective, windows []sloWindow) *slo {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].duration < windows[j].duration })
+
+	return &slo{
+		objectives: objectives,
+		windows:    windows,
+		series:     make(map[operationKey]*sloSeries),
+		now:        time.Now,
+		objectiveDesc: prometheus.NewDesc(
+			"aws_slo_objective_ratio",
+			"The target ratio of good requests to all requests.",
+			[]string{"service", "method"}, nil,
+		),
+		complianceDesc: prometheus.NewDesc(
+			"aws_slo_compliance_ratio",
+			"The ratio of good requests to all requests within the window.",
+			[]string{"service", "method", "window"}, nil,
+		),
+		burnRateDesc: prometheus.NewDesc(
+			"aws_slo_burn_rate",
+			"The rate the error budget is consumed at within the window. 1 means that the budget is used up exactly at the end of the SLO period.",
+			[]string{"service", "method", "window"}, nil,
+		),
+		budgetDesc: prometheus.NewDesc(
+			"aws_slo_error_budget_remaining_ratio",
+			"The ratio of the error budget remaining within the SLO period, which is the longest window.",
+			[]string{"service", "method"}, nil,
+		),
+	}
+}
+
+// parseSLOObjectives parses comma-separated objectives like `S3/GetObject=99.9@200ms,DynamoDB/*=99.5`.
+func parseSLOObjectives(v string) ([]sloObjective, error) {
+	var objectives []sloObjective
+
+	for _, entry := range strings.Split(v, ",") {
+		op, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("missing target in %q", entry)
+		}
+
+		service, method, ok := strings.Cut(op, "/")
+		if !ok || service == "" || method == "" {
+			return nil, fmt.Errorf("operation %q must be in the form of SERVICE/METHOD", op)
+		}
+
+		target, latency, hasLatency := strings.Cut(spec, "@")
+
+		percent, err := strconv.ParseFloat(target, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q, %v", target, err)
+		}
+		if percent <= 0 || percent >= 100 {
+			return nil, fmt.Errorf("target %q must be greater than 0 and less than 100", target)
+		}
+
+		o := sloObjective{service: service, method: method, target: percent / 100}
+
+		if hasLatency {
+			o.latency, err = time.ParseDuration(latency)
+			if err != nil {
+				return nil, fmt.Errorf("invalid latency %q, %v", latency, err)
+			}
+		}
+
+		objectives = append(objectives, o)
+	}
+
+	return objectives, nil
+}
+
+// parseSLOWindows parses comma-separated windows like `1h,6h,30d`.
+// In addition to the units supported by time.ParseDuration, `d` is accepted for days.
+func parseSLOWindows(v string) ([]sloWindow, error) {
+	var windows []sloWindow
+
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+
+		var (
+			d   time.Duration
+			err error
+		)
+		if days, ok := strings.CutSuffix(name, "d"); ok {
+			var n int
+			n, err = strconv.Atoi(days)
+			d = time.Duration(n) * 24 * time.Hour
+		} else {
+			d, err = time.ParseDuration(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q, %v", name, err)
+		}
+		if d < sloResolution {
+			return nil, fmt.Errorf("window %q must be at least %s", name, sloResolution)
+		}
+
+		windows = append(windows, sloWindow{name: name, duration: d})
+	}
+
+	return windows, nil
+}
+
+func (s *slo) observe(r result) {
+	var (
+		objective sloObjective
+		found     bool
+	)
+	// The objective of the method takes precedence over the one of "*", as expected outcomes do
+	for _, o := range s.objectives {
+		if o.service != r.service {
+			continue
+		}
+		if o.method == r.method {
+			objective, found = o, true
+			break
+		}
+		if o.method == "*" && !found {
+			objective, found = o, true
+		}
+	}
+	if !found {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := operationKey{service: r.service, method: r.method}
+	series, ok := s.series[key]
+	if !ok {
+		series = &sloSeries{objective: objective}
+		for _, w := range s.windows {
+			series.counters = append(series.counters, newSLOCounter(w.duration))
+		}
+		s.series[key] = series
+	}
+
+	good := objective.good(r)
+	for _, c := range series.counters {
+		c.add(r.time, good)
+	}
+}
+
+// reports returns the compliance of every operation an objective matched so far,
+// sorted by service and method.
+func (s *slo) reports() []sloReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	var reports []sloReport
+
+	for key, series := range s.series {
+		o := series.objective
+		budget := 1 - o.target
+
+		r := sloReport{
+			Service:                 key.service,
+			Method:                  key.method,
+			Objective:               o.target,
+			LatencyThresholdSeconds: o.latency.Seconds(),
+		}
+
+		for i, w := range s.windows {
+			// The current bucket is included, so that the shortest window reflects the latest results.
+			c := series.counters[i]
+			c.advance(now)
+			total, good := c.total, c.good
+
+			if total == 0 {
+				continue
+			}
+
+			compliance := float64(good) / float64(total)
+
+			r.Windows = append(r.Windows, sloWindowReport{
+				Window:     w.name,
+				Total:      total,
+				Good:       good,
+				Compliance: compliance,
+				BurnRate:   (1 - compliance) / budget,
+			})
+
+			if w == s.windows[len(s.windows)-1] {
+				remaining := 1 - (1-compliance)/budget
+				r.ErrorBudgetRemaining = &remaining
+			}
+		}
+
+		reports = append(reports, r)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Service != reports[j].Service {
+			return reports[i].Service < reports[j].Service
+		}
+		return reports[i].Method < reports[j].Method
+	})
+
+	return reports
+}
+
+func (s *slo) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.objectiveDesc
+	ch <- s.complianceDesc
+	ch <- s.burnRateDesc
+	ch <- s.budgetDesc
+}
+
+func (s *slo) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range s.reports() {
+		ch <- prometheus.MustNewConstMetric(s.objectiveDesc, prometheus.GaugeValue, r.Objective, r.Service, r.Method)
+
+		for _, w := range r.Windows {
+			ch <- prometheus.MustNewConstMetric(s.complianceDesc, prometheus.GaugeValue, w.Compliance, r.Service, r.Method, w.Window)
+			ch <- prometheus.MustNewConstMetric(s.burnRateDesc, prometheus.GaugeValue, w.BurnRate, r.Service, r.Method, w.Window)
+		}
+
+		if r.ErrorBudgetRemaining != nil {
+			ch <- prometheus.MustNewConstMetric(s.budgetDesc, prometheus.GaugeValue, *r.ErrorBudgetRemaining, r.Service, r.Method)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSLOObjectives(t *testing.T) {
+	objectives, err := parseSLOObjectives("S3/GetObject=99.5@200ms, DynamoDB/*=99")
+	require.NoError(t, err)
+	require.Equal(t, []sloObjective{
+		{service: "S3", method: "GetObject", target: 0.995, latency: 200 * time.Millisecond},
+		{service: "DynamoDB", method: "*", target: 0.99},
+	}, objectives)
+
+	for _, v := range []string{"S3/GetObject", "S3=99.9", "S3/GetObject=100", "S3/GetObject=99.9@fast"} {
+		_, err := parseSLOObjectives(v)
+		require.Error(t, err, v)
+	}
+}
+
+func TestParseSLOWindows(t *testing.T) {
+	windows, err := parseSLOWindows("5m,1h,30d")
+	require.NoError(t, err)
+	require.Equal(t, []sloWindow{
+		{name: "5m", duration: 5 * time.Minute},
+		{name: "1h", duration: time.Hour},
+		{name: "30d", duration: 30 * 24 * time.Hour},
+	}, windows)
+
+	_, err = parseSLOWindows("30s")
+	require.Error(t, err)
+}
+
+func TestSLO(t *testing.T) {
+	s := newSLO(
+		[]sloObjective{{service: "S3", method: "GetObject", target: 0.9, latency: 200 * time.Millisecond}},
+		[]sloWindow{{name: "1h", duration: time.Hour}, {name: "5m", duration: 5 * time.Minute}},
+	)
+
+	now := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	observe := func(ago time.Duration, duration time.Duration, err error) {
+		s.observe(result{service: "S3", method: "GetObject", duration: duration, err: err, time: now.Add(-ago)})
+	}
+
+	// 8 good results in the last 5 minutes
+	for i := 0; i < 8; i++ {
+		observe(time.Minute, 100*time.Millisecond, nil)
+	}
+	// A slow result and a failure in the last 5 minutes
+	observe(time.Minute, time.Second, nil)
+	observe(time.Minute, 100*time.Millisecond, errors.New("access denied"))
+	// 10 good results only in the last hour
+	for i := 0; i < 10; i++ {
+		observe(30*time.Minute, 100*time.Millisecond, nil)
+	}
+	// A result older than the longest window
+	observe(2*time.Hour, 100*time.Millisecond, errors.New("access denied"))
+	// A result for an operation without any objective
+	s.observe(result{service: "SQS", method: "ReceiveMessage", err: errors.New("access denied"), time: now})
+
+	reports := s.reports()
+	require.Len(t, reports, 1)
+
+	r := reports[0]
+	require.Equal(t, "S3", r.Service)
+	require.Equal(t, "GetObject", r.Method)
+	require.Equal(t, 0.9, r.Objective)
+	require.Equal(t, 0.2, r.LatencyThresholdSeconds)
+
+	require.Len(t, r.Windows, 2)
+
+	require.Equal(t, "5m", r.Windows[0].Window)
+	require.Equal(t, 10, r.Windows[0].Total)
+	require.Equal(t, 8, r.Windows[0].Good)
+	require.InDelta(t, 0.8, r.Windows[0].Compliance, 1e-9)
+	require.InDelta(t, 2, r.Windows[0].BurnRate, 1e-9)
+
+	require.Equal(t, "1h", r.Windows[1].Window)
+	require.Equal(t, 20, r.Windows[1].Total)
+	require.Equal(t, 18, r.Windows[1].Good)
+	require.InDelta(t, 0.9, r.Windows[1].Compliance, 1e-9)
+	require.InDelta(t, 1, r.Windows[1].BurnRate, 1e-9)
+
+	require.NotNil(t, r.ErrorBudgetRemaining)
+	require.InDelta(t, 0, *r.ErrorBudgetRemaining, 1e-9)
+}
+
+func TestSLOObjectivePrecedence(t *testing.T) {
+	s := newSLO(
+		[]sloObjective{
+			{service: "DynamoDB", method: "*", target: 0.99},
+			{service: "DynamoDB", method: "GetItem", target: 0.999},
+		},
+		[]sloWindow{{name: "1h", duration: time.Hour}},
+	)
+
+	now := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.observe(result{service: "DynamoDB", method: "GetItem", time: now})
+	s.observe(result{service: "DynamoDB", method: "PutItem", time: now})
+
+	reports := s.reports()
+	require.Len(t, reports, 2)
+
+	objectives := make(map[string]float64)
+	for _, r := range reports {
+		objectives[r.Method] = r.Objective
+	}
+	require.Equal(t, map[string]float64{"GetItem": 0.999, "PutItem": 0.99}, objectives)
+}
+
+func TestSLOCounter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	c := newSLOCounter(30 * 24 * time.Hour)
+	require.Equal(t, time.Hour, c.resolution)
+	require.Len(t, c.buckets, 720)
+
+	c.add(now, true)
+	c.add(now.Add(time.Hour), false)
+	c.advance(now.Add(2 * time.Hour))
+	require.Equal(t, 2, c.total)
+	require.Equal(t, 1, c.good)
+
+	// The first result falls out of the window
+	c.advance(now.Add(30 * 24 * time.Hour))
+	require.Equal(t, 1, c.total)
+	require.Equal(t, 0, c.good)
+
+	// Results older than the window are not counted
+	c.add(now, true)
+	require.Equal(t, 1, c.total)
+
+	// Every bucket expires after a long gap
+	c.advance(now.Add(90 * 24 * time.Hour))
+	require.Equal(t, 0, c.total)
+
+	c = newSLOCounter(5 * time.Minute)
+	require.Equal(t, time.Minute, c.resolution)
+	require.Len(t, c.buckets, 5)
+}
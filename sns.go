@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// configureSNS configures the SNS check from the environment variables.
+func (c *checker) configureSNS(cfg aws.Config) error {
+	c.snsTopicARN = os.Getenv("SNS_TOPIC_ARN")
+	c.snsDeliveryQueueURL = os.Getenv("SNS_DELIVERY_QUEUE_URL")
+
+	if c.snsTopicARN == "" {
+		if c.snsDeliveryQueueURL != "" {
+			return fmt.Errorf("SNS_TOPIC_ARN is required for SNS_DELIVERY_QUEUE_URL")
+		}
+		return nil
+	}
+
+	c.snsClient = sns.NewFromConfig(cfg, c.snsOpts...)
+
+	return nil
+}
+
+// doCheckSNS publishes a probe message to the topic,
+// and receives it from the queue subscribed to the topic, if any, to confirm the delivery.
+// The rest is skipped when the message has not been published or received.
+func (c *checker) doCheckSNS(ctx context.Context) {
+	var (
+		probeID       = c.probeID()
+		published     bool
+		publishedAt   time.Time
+		receiptHandle *string
+	)
+
+	ops := []operation{
+		{
+			method: "Publish",
+			operations: []func() error{
+				func() error {
+					publishedAt = time.Now()
+					_, err := c.snsClient.Publish(ctx, &sns.PublishInput{
+						TopicArn: &c.snsTopicARN,
+						Message:  aws.String(probeID),
+						MessageAttributes: map[string]snstypes.MessageAttributeValue{
+							sqsProbeIDAttribute: {
+								DataType:    aws.String("String"),
+								StringValue: aws.String(probeID),
+							},
+						},
+					})
+					if err != nil {
+						return err
+					}
+
+					published = true
+					return nil
+				},
+			},
+		},
+	}
+
+	if c.snsDeliveryQueueURL != "" {
+		ops = append(ops,
+			operation{
+				method: "ReceiveMessage",
+				operations: []func() error{
+					func() error {
+						if !published {
+							return errSkipOperation
+						}
+
+						return c.receiveSQSProbes(ctx, c.snsDeliveryQueueURL, probeID, func(msgs []sqstypes.Message) (bool, error) {
+							c.deliveryLatency.WithLabelValues("SNS", "Publish").Observe(time.Since(publishedAt).Seconds())
+							receiptHandle = msgs[0].ReceiptHandle
+							return true, nil
+						})
+					},
+				},
+			},
+			operation{
+				method: "DeleteMessage",
+				operations: []func() error{
+					func() error {
+						if receiptHandle == nil {
+							return errSkipOperation
+						}
+
+						_, err := c.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+							QueueUrl:      &c.snsDeliveryQueueURL,
+							ReceiptHandle: receiptHandle,
+						})
+						return err
+					},
+				},
+			},
+		)
+	}
+
+	c.doCheckService(ctx, "SNS", ops)
+}
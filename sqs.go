@@ -0,0 +1,671 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// sqsProbeIDAttribute is the message attribute carrying the probe ID of the messages sent by the checker.
+	sqsProbeIDAttribute = "AwsCheckerProbeId"
+	// sqsProbeSeqAttribute is the message attribute carrying the sequence number of a message within a FIFO burst.
+	sqsProbeSeqAttribute = "AwsCheckerProbeSeq"
+
+	// sqsReleaseVisibilityTimeout is the visibility timeout in seconds of the messages released by the check.
+	// It is not zero, so that the check does not receive the released messages again right away,
+	// and the instances sharing the queue do not keep passing them around.
+	sqsReleaseVisibilityTimeout = 1
+	// sqsStaleProbeMargin is added to the receive timeout before deleting the probe messages of other instances,
+	// so that a clock skew between the instances does not delete the probes that a live instance is still waiting for.
+	sqsStaleProbeMargin = time.Minute
+	// sqsOldestMessageAgeLookback is how far back the CloudWatch metric of the age of the oldest message is looked up.
+	// SQS publishes the metric every minute, and it takes a few minutes to show up.
+	sqsOldestMessageAgeLookback = 10 * time.Minute
+	// sqsOldestMessageAgePeriod is the period of the CloudWatch metric of the age of the oldest message,
+	// which is also the interval to read it at, as it changes no more often than that.
+	sqsOldestMessageAgePeriod = time.Minute
+
+	statusOrderViolation = "OrderViolation"
+	statusDuplicate      = "Duplicate"
+)
+
+// configureSQS configures the optional SQS checks from the environment variables.
+func (c *checker) configureSQS(cfg aws.Config) error {
+	var err error
+
+	c.sqsRoundTripCheck, err = envBool("SQS_ROUND_TRIP_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.sqsReceiveTimeout, err = envDuration("SQS_RECEIVE_TIMEOUT", 20*time.Second)
+	if err != nil {
+		return err
+	}
+
+	sqsVisibilityTimeout, err := envInt("SQS_VISIBILITY_TIMEOUT", 30)
+	if err != nil {
+		return err
+	}
+	c.sqsVisibilityTimeout = int32(sqsVisibilityTimeout)
+
+	c.sqsFIFO = strings.HasSuffix(c.sqsQueueURL, ".fifo")
+
+	c.sqsFIFOBurstSize, err = envInt("SQS_FIFO_BURST_SIZE", 5)
+	if err != nil {
+		return err
+	}
+
+	c.sqsQueueDepthCheck, err = envBool("SQS_QUEUE_DEPTH_CHECK")
+	if err != nil {
+		return err
+	}
+
+	c.sqsQueueMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_sqs_queue_messages",
+			Help: "Approximate number of messages in the SQS queue, by the state of the messages.",
+		},
+		[]string{"queue", "state"},
+	)
+
+	if c.sqsQueueDepthCheck {
+		c.cloudwatchClient = cloudwatch.NewFromConfig(cfg, c.cloudwatchOpts...)
+	}
+
+	c.sqsOldestMessageAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_sqs_queue_oldest_message_age_seconds",
+			Help: "Approximate age of the oldest message in the SQS queue, from the ApproximateAgeOfOldestMessage CloudWatch metric.",
+		},
+		[]string{"queue"},
+	)
+
+	c.sqsDeadLetterQueueMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_sqs_dead_letter_queue_messages",
+			Help: "Approximate number of messages in the dead-letter queue of the SQS queue, by the state of the messages.",
+		},
+		[]string{"queue", "dead_letter_queue", "state"},
+	)
+
+	// SendMessageBatch accepts up to 10 messages
+	if c.sqsFIFOBurstSize < 2 || c.sqsFIFOBurstSize > 10 {
+		return fmt.Errorf("SQS_FIFO_BURST_SIZE must be between 2 and 10, got %d", c.sqsFIFOBurstSize)
+	}
+
+	return nil
+}
+
+func (c *checker) doCheckSQS(ctx context.Context) {
+	var ops []operation
+
+	switch {
+	case c.sqsRoundTripCheck && c.sqsFIFO:
+		ops = c.sqsFIFOOperations(ctx)
+	case c.sqsRoundTripCheck:
+		ops = c.sqsRoundTripOperations(ctx)
+	default:
+		ops = []operation{
+			{
+				method: "ReceiveMessage",
+				operations: []func() error{
+					func() error {
+						_, err := c.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+							QueueUrl: &c.sqsQueueURL,
+						})
+						return err
+					},
+				},
+			},
+		}
+	}
+
+	if c.sqsQueueDepthCheck {
+		ops = append(ops, c.sqsQueueDepthOperations(ctx)...)
+	}
+
+	c.doCheckService(ctx, "SQS", ops)
+
+	// Only doCheckSQS reads and writes sqsOldestMessageAgeCheckedAt, so it needs no lock
+	if c.cloudwatchClient != nil && time.Since(c.sqsOldestMessageAgeCheckedAt) >= sqsOldestMessageAgePeriod {
+		c.sqsOldestMessageAgeCheckedAt = time.Now()
+		c.doCheckService(ctx, "CloudWatch", c.sqsOldestMessageAgeOperations(ctx))
+	}
+}
+
+// sqsQueueDepthOperations returns the operations to get the approximate number of messages in the queue,
+// and in its dead-letter queue if the queue has a redrive policy.
+func (c *checker) sqsQueueDepthOperations(ctx context.Context) []operation {
+	var deadLetterTargetARN string
+
+	return []operation{
+		{
+			method: "GetQueueAttributes",
+			operations: []func() error{
+				func() error {
+					attrs, err := c.getSQSQueueAttributes(ctx, c.sqsQueueURL, sqstypes.QueueAttributeNameRedrivePolicy)
+					if err != nil {
+						return err
+					}
+
+					c.setSQSQueueMessages(c.sqsQueueMessages.MustCurryWith(prometheus.Labels{"queue": sqsQueueName(c.sqsQueueURL)}), attrs)
+
+					if p := attrs[string(sqstypes.QueueAttributeNameRedrivePolicy)]; p != "" {
+						deadLetterTargetARN, err = sqsDeadLetterTargetARN(p)
+						if err != nil {
+							return err
+						}
+					}
+
+					return nil
+				},
+				func() error {
+					if deadLetterTargetARN == "" {
+						return nil
+					}
+
+					dlqURL, err := c.getSQSQueueURL(ctx, deadLetterTargetARN)
+					if err != nil {
+						return err
+					}
+
+					attrs, err := c.getSQSQueueAttributes(ctx, dlqURL)
+					if err != nil {
+						return err
+					}
+
+					c.setSQSQueueMessages(c.sqsDeadLetterQueueMessages.MustCurryWith(prometheus.Labels{
+						"queue":             sqsQueueName(c.sqsQueueURL),
+						"dead_letter_queue": sqsQueueName(dlqURL),
+					}), attrs)
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// sqsOldestMessageAgeOperations returns the operation to get the age of the oldest message in the queue from CloudWatch.
+func (c *checker) sqsOldestMessageAgeOperations(ctx context.Context) []operation {
+	return []operation{
+		{
+			method: "GetMetricData",
+			operations: []func() error{
+				func() error {
+					queue := sqsQueueName(c.sqsQueueURL)
+
+					age, ok, err := c.getSQSOldestMessageAge(ctx, queue)
+					if err != nil {
+						return err
+					}
+
+					// SQS does not publish the metric for a queue that has been inactive for a while
+					if !ok {
+						c.sqsOldestMessageAge.DeleteLabelValues(queue)
+						return nil
+					}
+
+					c.sqsOldestMessageAge.WithLabelValues(queue).Set(age)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// getSQSOldestMessageAge returns the latest ApproximateAgeOfOldestMessage of the queue in seconds from CloudWatch.
+// It returns false if there is no datapoint within sqsOldestMessageAgeLookback.
+func (c *checker) getSQSOldestMessageAge(ctx context.Context, queue string) (float64, bool, error) {
+	now := time.Now()
+
+	res, err := c.cloudwatchClient.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(now.Add(-sqsOldestMessageAgeLookback)),
+		EndTime:   aws.Time(now),
+		MetricDataQueries: []cloudwatchtypes.MetricDataQuery{
+			{
+				Id: aws.String("age"),
+				MetricStat: &cloudwatchtypes.MetricStat{
+					Metric: &cloudwatchtypes.Metric{
+						Namespace:  aws.String("AWS/SQS"),
+						MetricName: aws.String("ApproximateAgeOfOldestMessage"),
+						Dimensions: []cloudwatchtypes.Dimension{
+							{Name: aws.String("QueueName"), Value: aws.String(queue)},
+						},
+					},
+					Period: aws.Int32(int32(sqsOldestMessageAgePeriod.Seconds())),
+					Stat:   aws.String("Maximum"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	age, ok := latestMetricValue(res.MetricDataResults)
+	return age, ok, nil
+}
+
+// latestMetricValue returns the value of the latest datapoint in the results of GetMetricData.
+// It returns false if there is no datapoint.
+func latestMetricValue(results []cloudwatchtypes.MetricDataResult) (float64, bool) {
+	var (
+		latest   float64
+		latestAt time.Time
+		found    bool
+	)
+
+	for _, r := range results {
+		for i, at := range r.Timestamps {
+			if i >= len(r.Values) {
+				break
+			}
+			if !found || at.After(latestAt) {
+				latest, latestAt, found = r.Values[i], at, true
+			}
+		}
+	}
+
+	return latest, found
+}
+
+// getSQSQueueAttributes returns the approximate numbers of messages in the queue and the extra attributes.
+func (c *checker) getSQSQueueAttributes(ctx context.Context, queueURL string, extra ...sqstypes.QueueAttributeName) (map[string]string, error) {
+	res, err := c.sqsClient.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: &queueURL,
+		AttributeNames: append([]sqstypes.QueueAttributeName{
+			sqstypes.QueueAttributeNameApproximateNumberOfMessages,
+			sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			sqstypes.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+		}, extra...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Attributes, nil
+}
+
+// getSQSQueueURL returns the URL of the queue identified by the ARN,
+// which can be owned by another account.
+func (c *checker) getSQSQueueURL(ctx context.Context, queueARN string) (string, error) {
+	a, err := arn.Parse(queueARN)
+	if err != nil {
+		return "", fmt.Errorf("invalid queue ARN %q, %v", queueARN, err)
+	}
+
+	res, err := c.sqsClient.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName:              &a.Resource,
+		QueueOwnerAWSAccountId: &a.AccountID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(res.QueueUrl), nil
+}
+
+// setSQSQueueMessages sets the gauge curried with the queue labels to the approximate numbers of messages in the attributes.
+func (c *checker) setSQSQueueMessages(g *prometheus.GaugeVec, attrs map[string]string) {
+	for state, name := range map[string]sqstypes.QueueAttributeName{
+		"visible":   sqstypes.QueueAttributeNameApproximateNumberOfMessages,
+		"in_flight": sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		"delayed":   sqstypes.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+	} {
+		v, err := strconv.ParseFloat(attrs[string(name)], 64)
+		if err != nil {
+			log.Printf("invalid %s attribute %q, %v", name, attrs[string(name)], err)
+			continue
+		}
+		g.WithLabelValues(state).Set(v)
+	}
+}
+
+// sqsDeadLetterTargetARN returns the ARN of the dead-letter queue in the redrive policy of a queue.
+func sqsDeadLetterTargetARN(redrivePolicy string) (string, error) {
+	var p struct {
+		DeadLetterTargetARN string `json:"deadLetterTargetArn"`
+	}
+	if err := json.Unmarshal([]byte(redrivePolicy), &p); err != nil {
+		return "", fmt.Errorf("invalid redrive policy %q, %v", redrivePolicy, err)
+	}
+
+	return p.DeadLetterTargetARN, nil
+}
+
+// sqsQueueName returns the name of the queue, which is the last path segment of the queue URL.
+func sqsQueueName(queueURL string) string {
+	return queueURL[strings.LastIndex(queueURL, "/")+1:]
+}
+
+// sqsRoundTripOperations returns the operations to send a probe message, receive it back, and delete it.
+// The rest is skipped when the message has not been sent or received.
+func (c *checker) sqsRoundTripOperations(ctx context.Context) []operation {
+	var (
+		probeID       = c.probeID()
+		sent          bool
+		sentAt        time.Time
+		receiptHandle *string
+	)
+
+	return []operation{
+		{
+			method: "SendMessage",
+			operations: []func() error{
+				func() error {
+					sentAt = time.Now()
+					_, err := c.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+						QueueUrl:    &c.sqsQueueURL,
+						MessageBody: aws.String(probeID),
+						MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+							sqsProbeIDAttribute: {
+								DataType:    aws.String("String"),
+								StringValue: aws.String(probeID),
+							},
+						},
+					})
+					if err != nil {
+						return err
+					}
+
+					sent = true
+					return nil
+				},
+			},
+		},
+		{
+			method: "ReceiveMessage",
+			operations: []func() error{
+				func() error {
+					if !sent {
+						return errSkipOperation
+					}
+
+					return c.receiveSQSProbes(ctx, c.sqsQueueURL, probeID, func(msgs []sqstypes.Message) (bool, error) {
+						c.deliveryLatency.WithLabelValues("SQS", "SendMessage").Observe(time.Since(sentAt).Seconds())
+						receiptHandle = msgs[0].ReceiptHandle
+						return true, nil
+					})
+				},
+			},
+		},
+		{
+			method: "DeleteMessage",
+			operations: []func() error{
+				func() error {
+					if receiptHandle == nil {
+						return errSkipOperation
+					}
+
+					_, err := c.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+						QueueUrl:      &c.sqsQueueURL,
+						ReceiptHandle: receiptHandle,
+					})
+					return err
+				},
+			},
+		},
+	}
+}
+
+// sqsFIFOOperations returns the operations to send a numbered burst of probe messages to the FIFO queue,
+// and receive them back, checking that they arrive in order without duplicates.
+//
+// The received messages are deleted as they arrive, because a FIFO queue does not deliver
+// the next messages of a message group while the previous ones are in flight.
+// So the deletion is recorded as a part of ReceiveMessage, which is skipped when the burst has not been sent.
+func (c *checker) sqsFIFOOperations(ctx context.Context) []operation {
+	var (
+		probeID = c.probeID()
+		sent    bool
+		sentAt  time.Time
+	)
+
+	return []operation{
+		{
+			method: "SendMessageBatch",
+			operations: []func() error{
+				func() error {
+					var entries []sqstypes.SendMessageBatchRequestEntry
+					for i := 0; i < c.sqsFIFOBurstSize; i++ {
+						seq := strconv.Itoa(i)
+						entries = append(entries, sqstypes.SendMessageBatchRequestEntry{
+							Id:                     aws.String(seq),
+							MessageBody:            aws.String(probeID),
+							MessageGroupId:         aws.String(c.instanceID),
+							MessageDeduplicationId: aws.String(probeID + "-" + seq),
+							MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+								sqsProbeIDAttribute: {
+									DataType:    aws.String("String"),
+									StringValue: aws.String(probeID),
+								},
+								sqsProbeSeqAttribute: {
+									DataType:    aws.String("Number"),
+									StringValue: aws.String(seq),
+								},
+							},
+						})
+					}
+
+					sentAt = time.Now()
+					res, err := c.sqsClient.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+						QueueUrl: &c.sqsQueueURL,
+						Entries:  entries,
+					})
+					if err != nil {
+						return err
+					}
+					if len(res.Failed) > 0 {
+						f := res.Failed[0]
+						return fmt.Errorf("failed to send %d of %d messages: %s: %s", len(res.Failed), len(entries), aws.ToString(f.Code), aws.ToString(f.Message))
+					}
+
+					sent = true
+					return nil
+				},
+			},
+		},
+		{
+			method: "ReceiveMessage",
+			operations: []func() error{
+				func() error {
+					if !sent {
+						return errSkipOperation
+					}
+
+					var (
+						next      int
+						seen      = make(map[int]bool)
+						violation error
+					)
+
+					err := c.receiveSQSProbes(ctx, c.sqsQueueURL, probeID, func(msgs []sqstypes.Message) (bool, error) {
+						if err := c.deleteSQSMessages(ctx, c.sqsQueueURL, msgs); err != nil {
+							return false, err
+						}
+
+						for _, msg := range msgs {
+							seq, err := strconv.Atoi(aws.ToString(msg.MessageAttributes[sqsProbeSeqAttribute].StringValue))
+							if err != nil {
+								return false, fmt.Errorf("invalid sequence number of probe message %s, %v", aws.ToString(msg.MessageId), err)
+							}
+
+							switch {
+							case seen[seq]:
+								if violation == nil {
+									violation = withStatus(statusDuplicate, fmt.Errorf("probe message %d was received more than once", seq))
+								}
+							case seq != next:
+								if violation == nil {
+									violation = withStatus(statusOrderViolation, fmt.Errorf("probe message %d was received when %d was expected", seq, next))
+								}
+							}
+
+							seen[seq] = true
+							next = seq + 1
+						}
+
+						if len(seen) < c.sqsFIFOBurstSize {
+							return false, nil
+						}
+
+						c.deliveryLatency.WithLabelValues("SQS", "SendMessageBatch").Observe(time.Since(sentAt).Seconds())
+						return true, nil
+					})
+					if err != nil {
+						return err
+					}
+
+					return violation
+				},
+			},
+		},
+	}
+}
+
+// receiveSQSProbes long-polls the queue and passes the received messages of the probe to handle,
+// until handle returns true or the receive timeout passes.
+//
+// The other messages are handled so that the check can share the queue:
+// Stale probe messages older than the receive timeout plus sqsStaleProbeMargin, like the ones left behind by stopped instances,
+// are deleted, whichever instance sent them, as no check waits for them anymore.
+// The other messages, including the probes other checks are waiting for, are made visible again
+// after sqsReleaseVisibilityTimeout, so that their consumers receive them without waiting for the visibility timeout.
+func (c *checker) receiveSQSProbes(ctx context.Context, queueURL, probeID string, handle func([]sqstypes.Message) (bool, error)) error {
+	deadline := time.Now().Add(c.sqsReceiveTimeout)
+
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out waiting for the probe messages")
+		}
+
+		// 20 seconds is the maximum wait time of long polling
+		wait := min(int32(remaining.Seconds()), 20)
+
+		res, err := c.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              &queueURL,
+			MaxNumberOfMessages:   10,
+			WaitTimeSeconds:       wait,
+			VisibilityTimeout:     c.sqsVisibilityTimeout,
+			MessageAttributeNames: []string{sqsProbeIDAttribute, sqsProbeSeqAttribute},
+		})
+		if err != nil {
+			return err
+		}
+
+		var probes []sqstypes.Message
+		for _, msg := range res.Messages {
+			id := sqsProbeID(msg)
+			switch {
+			case id == probeID:
+				probes = append(probes, msg)
+			case c.staleSQSProbe(id):
+				c.deleteSQSMessage(ctx, queueURL, msg)
+			default:
+				c.releaseSQSMessage(ctx, queueURL, msg)
+			}
+		}
+
+		if len(probes) == 0 {
+			continue
+		}
+
+		done, err := handle(probes)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// staleSQSProbe returns true if id is the probe ID of a message sent longer than the receive timeout
+// plus sqsStaleProbeMargin ago.
+// The time the message was sent at is read from the clock of the sender, which may be skewed from the local one.
+func (c *checker) staleSQSProbe(id string) bool {
+	_, sentAt, ok := parseProbeID(id)
+	return ok && time.Since(sentAt) > c.sqsReceiveTimeout+sqsStaleProbeMargin
+}
+
+// sqsProbeID returns the probe ID of the message, or an empty string for a foreign message.
+//
+// The probe ID of a message delivered from an SNS topic without raw message delivery
+// is read from the message attributes in the SNS envelope of the body.
+func sqsProbeID(msg sqstypes.Message) string {
+	if attr, ok := msg.MessageAttributes[sqsProbeIDAttribute]; ok {
+		return aws.ToString(attr.StringValue)
+	}
+
+	var envelope struct {
+		Type              string
+		MessageAttributes map[string]struct {
+			Value string
+		}
+	}
+	if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &envelope); err != nil || envelope.Type != "Notification" {
+		return ""
+	}
+
+	return envelope.MessageAttributes[sqsProbeIDAttribute].Value
+}
+
+func (c *checker) deleteSQSMessage(ctx context.Context, queueURL string, msg sqstypes.Message) {
+	if _, err := c.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &queueURL,
+		ReceiptHandle: msg.ReceiptHandle,
+	}); err != nil {
+		log.Printf("failed to delete stale probe message %s, %v", aws.ToString(msg.MessageId), err)
+	}
+}
+
+// deleteSQSMessages deletes the messages in a batch.
+func (c *checker) deleteSQSMessages(ctx context.Context, queueURL string, msgs []sqstypes.Message) error {
+	var entries []sqstypes.DeleteMessageBatchRequestEntry
+	for i, msg := range msgs {
+		entries = append(entries, sqstypes.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+	}
+
+	res, err := c.sqsClient.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: &queueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		return err
+	}
+	if len(res.Failed) > 0 {
+		f := res.Failed[0]
+		return fmt.Errorf("failed to delete %d of %d messages: %s: %s", len(res.Failed), len(entries), aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+
+	return nil
+}
+
+func (c *checker) releaseSQSMessage(ctx context.Context, queueURL string, msg sqstypes.Message) {
+	if _, err := c.sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &queueURL,
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: sqsReleaseVisibilityTimeout,
+	}); err != nil {
+		log.Printf("failed to release foreign message %s, %v", aws.ToString(msg.MessageId), err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQSProbeID(t *testing.T) {
+	require.Equal(t, "host-1-1", sqsProbeID(sqstypes.Message{
+		Body: aws.String("host-1-1"),
+		MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+			sqsProbeIDAttribute: {DataType: aws.String("String"), StringValue: aws.String("host-1-1")},
+		},
+	}))
+
+	require.Equal(t, "host-1-2", sqsProbeID(sqstypes.Message{
+		Body: aws.String(`{"Type":"Notification","Message":"host-1-2","MessageAttributes":{"AwsCheckerProbeId":{"Type":"String","Value":"host-1-2"}}}`),
+	}))
+
+	require.Empty(t, sqsProbeID(sqstypes.Message{Body: aws.String(`{"Type":"Notification","Message":"hello"}`)}))
+	require.Empty(t, sqsProbeID(sqstypes.Message{Body: aws.String("hello")}))
+}
+
+func TestStaleSQSProbe(t *testing.T) {
+	c := &checker{sqsReceiveTimeout: 20 * time.Second}
+
+	old := fmt.Sprintf("aws-checker-%d-1", time.Now().Add(-5*time.Minute).UnixNano())
+	recent := fmt.Sprintf("aws-checker-2-%d-1", time.Now().UnixNano())
+	// A probe sent just after the receive timeout ago may be from a live instance with a skewed clock
+	skewed := fmt.Sprintf("aws-checker-2-%d-1", time.Now().Add(-30*time.Second).UnixNano())
+
+	require.True(t, c.staleSQSProbe(old))
+	require.False(t, c.staleSQSProbe(recent))
+	require.False(t, c.staleSQSProbe(skewed))
+	require.False(t, c.staleSQSProbe("hello"))
+	require.False(t, c.staleSQSProbe(""))
+}
+
+func TestLatestMetricValue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	age, ok := latestMetricValue([]cloudwatchtypes.MetricDataResult{
+		{
+			Timestamps: []time.Time{now.Add(-2 * time.Minute), now, now.Add(-time.Minute)},
+			Values:     []float64{120, 30, 60},
+		},
+	})
+	require.True(t, ok)
+	require.Equal(t, 30.0, age)
+
+	_, ok = latestMetricValue([]cloudwatchtypes.MetricDataResult{{}})
+	require.False(t, ok)
+	_, ok = latestMetricValue(nil)
+	require.False(t, ok)
+}
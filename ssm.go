@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// configureSSM configures the SSM Parameter Store check from the environment variables.
+func (c *checker) configureSSM(cfg aws.Config) error {
+	c.ssmParameterName = os.Getenv("SSM_PARAMETER_NAME")
+	c.ssmParametersPath = os.Getenv("SSM_PARAMETERS_PATH")
+	if c.ssmParameterName == "" && c.ssmParametersPath == "" {
+		return nil
+	}
+
+	c.ssmClient = ssm.NewFromConfig(cfg, c.ssmOpts...)
+	c.ssmExpectedSHA256 = os.Getenv("SSM_PARAMETER_EXPECTED_SHA256")
+
+	var err error
+	c.ssmWithDecryption, err = envBool("SSM_WITH_DECRYPTION")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// doCheckSSM reads the parameter and the parameters under the path, whichever is configured.
+// The values are never logged or exported, and only the hash of the single parameter is compared.
+func (c *checker) doCheckSSM(ctx context.Context) {
+	var ops []operation
+
+	if c.ssmParameterName != "" {
+		ops = append(ops, operation{
+			method: "GetParameter",
+			operations: []func() error{
+				func() error {
+					res, err := c.ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
+						Name:           &c.ssmParameterName,
+						WithDecryption: aws.Bool(c.ssmWithDecryption),
+					})
+					if err != nil {
+						return err
+					}
+
+					if !secretSHA256Equal([]byte(aws.ToString(res.Parameter.Value)), c.ssmExpectedSHA256) {
+						return withStatus(statusCorruptData, fmt.Errorf("sha256 of parameter %s does not match the expected one", c.ssmParameterName))
+					}
+					return nil
+				},
+			},
+		})
+	}
+
+	if c.ssmParametersPath != "" {
+		ops = append(ops, operation{
+			method: "GetParametersByPath",
+			operations: []func() error{
+				func() error {
+					// A single page is enough to confirm the access to the path
+					res, err := c.ssmClient.GetParametersByPath(ctx, &ssm.GetParametersByPathInput{
+						Path:           &c.ssmParametersPath,
+						Recursive:      aws.Bool(true),
+						WithDecryption: aws.Bool(c.ssmWithDecryption),
+					})
+					if err != nil {
+						return err
+					}
+
+					if len(res.Parameters) == 0 && res.NextToken == nil {
+						return fmt.Errorf("no parameters found under %s", c.ssmParametersPath)
+					}
+					return nil
+				},
+			},
+		})
+	}
+
+	c.doCheckService(ctx, "SSM", ops)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statusTracker keeps the latest result of every operation for the status API.
+type statusTracker struct {
+	mu      sync.Mutex
+	results map[operationKey]result
+}
+
+// statusResponse is the response body of the status API.
+type statusResponse struct {
+	Operations []operationStatus `json:"operations"`
+	SLOs       []sloReport       `json:"slos,omitempty"`
+	// RootCause is the failing operation that likely causes the failures of the others,
+	// like STS failing to resolve the credentials every other check depends on.
+	RootCause string `json:"root_cause,omitempty"`
+}
+
+type operationStatus struct {
+	Service             string    `json:"service"`
+	Method              string    `json:"method"`
+	Status              string    `json:"status"`
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	LastDurationSeconds float64   `json:"last_duration_seconds"`
+	LastError           string    `json:"last_error,omitempty"`
+	Health              string    `json:"health"`
+	Flapping            bool      `json:"flapping"`
+	RootCause           bool      `json:"root_cause,omitempty"`
+}
+
+func newStatusTracker() *statusTracker {
+	return &statusTracker{
+		results: make(map[operationKey]result),
+	}
+}
+
+func (s *statusTracker) observe(r result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[operationKey{service: r.service, method: r.method}] = r
+}
+
+// operations returns the latest status of every operation checked so far,
+// sorted by service and method.
+func (s *statusTracker) operations() []operationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ops := make([]operationStatus, 0, len(s.results))
+	for _, r := range s.results {
+		op := operationStatus{
+			Service:             r.service,
+			Method:              r.method,
+			Status:              r.status,
+			LastCheckedAt:       r.time,
+			LastDurationSeconds: r.duration.Seconds(),
+		}
+		if r.err != nil {
+			op.LastError = r.err.Error()
+		}
+		ops = append(ops, op)
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Service != ops[j].Service {
+			return ops[i].Service < ops[j].Service
+		}
+		return ops[i].Method < ops[j].Method
+	})
+
+	return ops
+}
+
+// statusHandler serves the latest status and health of every operation and the SLO compliance as JSON.
+func (c *checker) statusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := statusResponse{
+			Operations: c.status.operations(),
+		}
+		for i, op := range res.Operations {
+			if h, ok := c.health.status(op.Service, op.Method); ok {
+				res.Operations[i].Health = h.state
+				res.Operations[i].Flapping = h.flapping
+			}
+		}
+		res.RootCause = rootCause(res.Operations)
+		if c.slo != nil {
+			res.SLOs = c.slo.reports()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Printf("failed to write status response, %v", err)
+		}
+	})
+}
+
+// rootCause flags the failing STS operations as the root cause and returns the first of them as "service/method".
+// It returns an empty string when STS is not failing.
+func rootCause(ops []operationStatus) string {
+	var cause string
+	for i, op := range ops {
+		if op.Service != "STS" || op.Status == "Success" {
+			continue
+		}
+		ops[i].RootCause = true
+		if cause == "" {
+			cause = op.Service + "/" + op.Method
+		}
+	}
+	return cause
+}
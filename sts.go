@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// configureSTS configures the STS check, which is enabled unless STS_CHECK_DISABLED is true.
+func (c *checker) configureSTS(cfg aws.Config) error {
+	disabled, err := envBool("STS_CHECK_DISABLED")
+	if err != nil {
+		return err
+	}
+	if disabled {
+		return nil
+	}
+
+	c.callerIdentity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_caller_identity_info",
+			Help: "The identity of the credentials resolved by the checker, returned by STS GetCallerIdentity. Always 1.",
+		},
+		[]string{"account", "arn", "user_id"},
+	)
+
+	// A vector without labels, so that nothing is exported for the credentials that do not expire
+	c.credentialsExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "aws_credentials_expiry_seconds",
+			Help: "Seconds until the current credentials expire. Not exported for credentials that do not expire.",
+		},
+		nil,
+	)
+
+	c.credentials = cfg.Credentials
+	c.stsClient = sts.NewFromConfig(cfg, c.stsOpts...)
+
+	return nil
+}
+
+// doCheckSTS retrieves the credentials and checks the identity of them with GetCallerIdentity.
+//
+// Missing or expired credentials fail every other check too,
+// so the status API reports failures of this check as the suspected root cause of the others.
+func (c *checker) doCheckSTS(ctx context.Context) {
+	c.doCheckService(ctx, "STS", []operation{
+		{
+			method: "RetrieveCredentials",
+			operations: []func() error{
+				func() error {
+					if c.credentials == nil {
+						return fmt.Errorf("no credentials are configured")
+					}
+
+					creds, err := c.credentials.Retrieve(ctx)
+					if err != nil {
+						return err
+					}
+
+					if creds.CanExpire {
+						c.credentialsExpiry.WithLabelValues().Set(time.Until(creds.Expires).Seconds())
+					} else {
+						c.credentialsExpiry.Reset()
+					}
+					return nil
+				},
+			},
+		},
+		{
+			method: "GetCallerIdentity",
+			operations: []func() error{
+				func() error {
+					res, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+					if err != nil {
+						return err
+					}
+
+					// The identity changes when the credentials are refreshed with another role
+					c.callerIdentity.Reset()
+					c.callerIdentity.WithLabelValues(aws.ToString(res.Account), aws.ToString(res.Arn), aws.ToString(res.UserId)).Set(1)
+					return nil
+				},
+			},
+		},
+	})
+}
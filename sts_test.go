@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoCheckSTS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::123456789012:assumed-role/aws-checker/session</Arn>
+    <UserId>AROAEXAMPLE:session</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata>
+    <RequestId>01234567-89ab-cdef-0123-456789abcdef</RequestId>
+  </ResponseMetadata>
+</GetCallerIdentityResponse>`))
+	}))
+	defer srv.Close()
+
+	var creds aws.Credentials
+
+	cfg := testAWSConfig(srv.URL)
+	cfg.Credentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return creds, nil
+	})
+
+	t.Setenv("STS_CHECK_DISABLED", "true")
+	c := newTestChecker()
+	require.NoError(t, c.configureSTS(cfg))
+	require.Nil(t, c.stsClient)
+	require.Nil(t, c.credentialsExpiry)
+
+	// The check is enabled by default
+	t.Setenv("STS_CHECK_DISABLED", "")
+	c = newTestChecker()
+	require.NoError(t, c.configureSTS(cfg))
+	require.Contains(t, c.collectors(), c.credentialsExpiry)
+
+	creds = aws.Credentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", CanExpire: true, Expires: time.Now().Add(time.Hour)}
+	c.doCheckSTS(context.Background())
+
+	for _, op := range c.status.operations() {
+		require.Equal(t, "Success", op.Status, op.Method)
+	}
+	require.InDelta(t, time.Hour.Seconds(), testutil.ToFloat64(c.credentialsExpiry), 60)
+	require.Equal(t, 1.0, testutil.ToFloat64(c.callerIdentity.WithLabelValues("123456789012", "arn:aws:sts::123456789012:assumed-role/aws-checker/session", "AROAEXAMPLE:session")))
+
+	// Nothing is exported for the credentials that do not expire
+	creds = aws.Credentials{AccessKeyID: "AKIAEXAMPLE", SecretAccessKey: "secret"}
+	c.doCheckSTS(context.Background())
+	require.Equal(t, 0, testutil.CollectAndCount(c.credentialsExpiry))
+}